@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var accountFlag string
+
+// configAccount is one Apple Ads account (org login) in the config file:
+//
+//	accounts:
+//	  client-a:
+//	    description: Client A org
+//	    cookie-file: ~/.aads/accounts/client-a/app_ads_cookie.txt
+//	    profile-dir: ~/.aads/accounts/client-a/playwright-profile
+//	    adam-id: 1234567890
+//
+// Every field is optional; an account that is not configured at all still gets its
+// own cookie file and browser profile under ~/.aads/accounts/NAME.
+type configAccount struct {
+	Description string `yaml:"description"`
+	CookieFile  string `yaml:"cookie-file"`
+	ProfileDir  string `yaml:"profile-dir"`
+	AdamID      int64  `yaml:"adam-id"`
+}
+
+type asoAccountRow struct {
+	Account     string `json:"account"`
+	Selected    bool   `json:"selected"`
+	Description string `json:"description,omitempty"`
+	AdamID      int64  `json:"adamId,omitempty"`
+	CookieFile  string `json:"cookieFile"`
+	ProfileDir  string `json:"profileDir"`
+	Session     string `json:"session"`
+	Expires     string `json:"expires,omitempty"`
+	Campaigns   *int   `json:"campaigns,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// Session health values reported by checkCMSession.
+const (
+	cmSessionValid     = "valid"
+	cmSessionExpired   = "expired"
+	cmSessionNotOwned  = "not-owned"
+	cmSessionMissing   = "missing"
+	cmSessionError     = "error"
+	cmSessionUnchecked = "unchecked"
+)
+
+// defaultAccountName is the account used without --account: the top-level cookie file
+// and browser profile. The name is reserved, so --account default selects them too.
+const defaultAccountName = "default"
+
+// validateAccountName rejects names that would not map to exactly one directory
+// under ~/.aads/accounts.
+func validateAccountName(name string) error {
+	if name == "" || name == "." || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid account name %q: it must not be empty or contain path separators or \"..\"", name)
+	}
+	return nil
+}
+
+// validateAccounts checks the names of the configured accounts.
+func (c *configFile) validateAccounts() error {
+	for name := range c.Accounts {
+		if name == defaultAccountName {
+			return fmt.Errorf("account name %q is reserved for the cookie file used without --account", name)
+		}
+		if err := validateAccountName(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func defaultAccountsDir() string {
+	return filepath.Join(filepath.Dir(defaultCMCookieFilePath()), "accounts")
+}
+
+// account returns the settings of a named account, with unset paths defaulting to
+// the account's own directory.
+func (c *configFile) account(name string) configAccount {
+	acc := c.Accounts[name]
+	acc.CookieFile = expandHome(strings.TrimSpace(acc.CookieFile))
+	acc.ProfileDir = expandHome(strings.TrimSpace(acc.ProfileDir))
+	if acc.CookieFile == "" {
+		acc.CookieFile = filepath.Join(defaultAccountsDir(), name, "app_ads_cookie.txt")
+	}
+	if acc.ProfileDir == "" {
+		acc.ProfileDir = filepath.Join(defaultAccountsDir(), name, "playwright-profile")
+	}
+	return acc
+}
+
+// accountFlagValues maps an account onto the flags it provides defaults for. Both
+// the keyword commands' names and cm-cookie's names are listed.
+func accountFlagValues(acc configAccount) map[string]any {
+	values := map[string]any{
+		"cookie-file":        acc.CookieFile,
+		"out":                acc.CookieFile,
+		"cookie-profile-dir": acc.ProfileDir,
+		"profile-dir":        acc.ProfileDir,
+	}
+	if acc.AdamID > 0 {
+		values["adam-id"] = acc.AdamID
+	}
+	return values
+}
+
+// accountNames lists configured accounts plus any account directories created by
+// earlier cookie refreshes.
+func (c *configFile) accountNames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for name := range c.Accounts {
+		seen[name] = true
+		names = append(names, name)
+	}
+	entries, _ := os.ReadDir(defaultAccountsDir())
+	for _, e := range entries {
+		if e.IsDir() && !seen[e.Name()] && e.Name() != defaultAccountName && validateAccountName(e.Name()) == nil {
+			seen[e.Name()] = true
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkCMSession makes one lightweight authenticated call (campaigns/find) to tell
+// whether cookie is still accepted by Apple Ads.
+func checkCMSession(ctx context.Context, cookie string, extraHeaders map[string]string, timeout time.Duration) (string, []cmCampaignItem, error) {
+	if strings.TrimSpace(cookie) == "" {
+		return cmSessionMissing, nil, nil
+	}
+	reqCtx, cancel := withOptionalTimeout(ctx, timeout)
+	defer cancel()
+	campaigns, err := cmCampaignsFind(reqCtx, cookie, extraHeaders)
+	switch {
+	case err == nil:
+		return cmSessionValid, campaigns, nil
+	case isCMNoUserOwnedAppsError(err):
+		return cmSessionNotOwned, nil, err
+	case isCMRefreshError(err):
+		return cmSessionExpired, nil, err
+	default:
+		return cmSessionError, nil, err
+	}
+}
+
+func newASOAccountsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "accounts",
+		Short: "List Apple Ads accounts (cookie jars) and their session health",
+		Long: "List the accounts from the config file and ~/.aads/accounts, plus the default account used without --account.\n" +
+			"Each account has its own cookie file and browser profile; select one with --account NAME on any command.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cfg, _, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			check, _ := cmd.Flags().GetBool("check")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			selected := strings.TrimSpace(accountFlag)
+
+			rows := []asoAccountRow{{
+				Account:    defaultAccountName,
+				Selected:   selected == "" || selected == defaultAccountName,
+				CookieFile: defaultCMCookieFilePath(),
+				ProfileDir: defaultCMCookieProfileDir(),
+			}}
+			for _, name := range cfg.accountNames() {
+				acc := cfg.account(name)
+				rows = append(rows, asoAccountRow{
+					Account:     name,
+					Selected:    name == selected,
+					Description: acc.Description,
+					AdamID:      acc.AdamID,
+					CookieFile:  acc.CookieFile,
+					ProfileDir:  acc.ProfileDir,
+				})
+			}
+
+			for i := range rows {
+				row := &rows[i]
+				cookie, err := readCookieFile(row.CookieFile)
+				if err != nil {
+					row.Session, row.Detail = cmSessionError, err.Error()
+					continue
+				}
+				if cookie == "" {
+					row.Session = cmSessionMissing
+					continue
+				}
+				if exp, _, ok := storedCookieExpiry(row.CookieFile); ok {
+					row.Expires = exp.UTC().Format(time.RFC3339)
+				}
+				if !check {
+					row.Session = cmSessionUnchecked
+					continue
+				}
+				status, campaigns, err := checkCMSession(ctx, cookie, nil, timeout)
+				row.Session = status
+				if err != nil {
+					row.Detail = err.Error()
+				} else {
+					n := len(campaigns)
+					row.Campaigns = &n
+				}
+			}
+			return printOutput(rows)
+		},
+	}
+
+	cmd.Flags().Bool("check", true, "Check each stored session with a lightweight Apple Ads call")
+	cmd.Flags().Duration("timeout", 20*time.Second, "Timeout per session check")
+	return cmd
+}
@@ -8,7 +8,6 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"regexp"
 	"strconv"
 	"strings"
@@ -33,15 +32,42 @@ type itunesAPIResponse struct {
 	Results     []itunesAppEntry `json:"results"`
 }
 
+// itunesAppEntry is a software result of iTunes Search/Lookup. The API has no
+// subtitle; see fetchAppSubtitle.
 type itunesAppEntry struct {
-	TrackID   int64  `json:"trackId"`
-	TrackName string `json:"trackName"`
-	BundleID  string `json:"bundleId"`
+	TrackID    int64  `json:"trackId"`
+	TrackName  string `json:"trackName"`
+	BundleID   string `json:"bundleId"`
+	ArtistName string `json:"artistName"`
+	SellerName string `json:"sellerName"`
+
+	Description      string   `json:"description"`
+	ReleaseNotes     string   `json:"releaseNotes"`
+	PrimaryGenreName string   `json:"primaryGenreName"`
+	Genres           []string `json:"genres"`
+	Languages        []string `json:"languageCodesISO2A"`
+	ContentRating    string   `json:"contentAdvisoryRating"`
+	MinimumOSVersion string   `json:"minimumOsVersion"`
+	FileSizeBytes    string   `json:"fileSizeBytes"`
+	TrackViewURL     string   `json:"trackViewUrl"`
+
+	AverageUserRating                  float64 `json:"averageUserRating"`
+	UserRatingCount                    int64   `json:"userRatingCount"`
+	AverageUserRatingForCurrentVersion float64 `json:"averageUserRatingForCurrentVersion"`
+	UserRatingCountForCurrentVersion   int64   `json:"userRatingCountForCurrentVersion"`
+
+	Version                   string  `json:"version"`
+	ReleaseDate               string  `json:"releaseDate"`
+	CurrentVersionReleaseDate string  `json:"currentVersionReleaseDate"`
+	Price                     float64 `json:"price"`
+	FormattedPrice            string  `json:"formattedPrice"`
+	Currency                  string  `json:"currency"`
 }
 
 func resolveAdamIDFromFlags(ctx context.Context, cmd *cobra.Command, countries []string) (int64, error) {
 	adamID, _ := cmd.Flags().GetInt64("adam-id")
 	if adamID > 0 {
+		recordRunAdamID(adamID, adamIDSourceFlag, "")
 		return adamID, nil
 	}
 
@@ -51,7 +77,8 @@ func resolveAdamIDFromFlags(ctx context.Context, cmd *cobra.Command, countries [
 		if err != nil {
 			return 0, fmt.Errorf("parse --app-url: %w", err)
 		}
-		fmt.Fprintf(os.Stderr, "Resolved adam-id=%d from --app-url\n", id)
+		logInfo("adam_resolved", fmt.Sprintf("Resolved adam-id=%d from --app-url", id), "adam_id", id, "source", adamIDSourceAppURL)
+		recordRunAdamID(id, adamIDSourceAppURL, "")
 		return id, nil
 	}
 
@@ -64,23 +91,35 @@ func resolveAdamIDFromFlags(ctx context.Context, cmd *cobra.Command, countries [
 		if err != nil {
 			return 0, fmt.Errorf("resolve from --bundle-id: %w", err)
 		}
+		msg := fmt.Sprintf("Resolved adam-id=%d from bundle-id %q", id, bundleID)
 		if appName != "" {
-			fmt.Fprintf(os.Stderr, "Resolved adam-id=%d from bundle-id %q (%s)\n", id, bundleID, appName)
-		} else {
-			fmt.Fprintf(os.Stderr, "Resolved adam-id=%d from bundle-id %q\n", id, bundleID)
+			msg += fmt.Sprintf(" (%s)", appName)
 		}
+		logInfo("adam_resolved", msg, "adam_id", id, "source", adamIDSourceBundleID, "bundle_id", bundleID, "app_name", appName)
+		recordRunAdamID(id, adamIDSourceBundleID, appName)
 		return id, nil
 	}
 
 	appName, _ := cmd.Flags().GetString("app-name")
 	appName = strings.TrimSpace(appName)
 	if appName != "" {
-		id, resolvedName, resolvedBundleID, err := searchAdamIDByAppName(ctx, appName, lookupCountry)
+		minConfidence, err := cmd.Flags().GetFloat64("min-confidence")
+		if err != nil {
+			minConfidence = defaultMinAppNameConfidence
+		}
+		candidates, err := searchAppNameCandidates(ctx, appName, lookupCountry, appNameSearchLimit)
 		if err != nil {
 			return 0, fmt.Errorf("resolve from --app-name: %w", err)
 		}
-		fmt.Fprintf(os.Stderr, "Resolved adam-id=%d from app-name %q -> %q (%s)\n", id, appName, resolvedName, resolvedBundleID)
-		return id, nil
+		c, err := selectAppNameCandidate(appName, candidates, minConfidence, stdinIsTerminal())
+		if err != nil {
+			return 0, fmt.Errorf("resolve from --app-name: %w", err)
+		}
+		logInfo("adam_resolved", fmt.Sprintf("Resolved adam-id=%d from app-name %q -> %q (%s, score %.2f)", c.AdamID, appName, c.Name, c.BundleID, c.Score),
+			"adam_id", c.AdamID, "source", adamIDSourceAppName, "query", appName, "app_name", c.Name, "bundle_id", c.BundleID,
+			"developer", c.Developer, "score", c.Score)
+		recordRunAdamID(c.AdamID, adamIDSourceAppName, c.Name)
+		return c.AdamID, nil
 	}
 
 	return 0, fmt.Errorf("%w: --adam-id is required (or provide --app-url, --bundle-id, or --app-name)", errAdamIDNotProvided)
@@ -181,38 +220,52 @@ func lookupAdamIDByBundleID(ctx context.Context, bundleID, country string) (int6
 	return 0, "", fmt.Errorf("no valid adam-id found for bundle-id %q", bundleID)
 }
 
-func searchAdamIDByAppName(ctx context.Context, appName, country string) (int64, string, string, error) {
+// lookupAppsByID looks up many adam-ids in one storefront. Apps that are not
+// available there are missing from the result.
+func lookupAppsByID(ctx context.Context, ids []int64, country string) (map[int64]itunesAppEntry, error) {
+	const batch = 100
+	out := map[int64]itunesAppEntry{}
+	for start := 0; start < len(ids); start += batch {
+		chunk := ids[start:min(start+batch, len(ids))]
+		parts := make([]string, 0, len(chunk))
+		for _, id := range chunk {
+			parts = append(parts, strconv.FormatInt(id, 10))
+		}
+		q := url.Values{}
+		q.Set("id", strings.Join(parts, ","))
+		q.Set("country", strings.ToLower(strings.TrimSpace(country)))
+
+		var resp itunesAPIResponse
+		if err := itunesGetJSON(ctx, itunesLookupURL, q, &resp); err != nil {
+			return nil, err
+		}
+		for _, it := range resp.Results {
+			if it.TrackID > 0 {
+				out[it.TrackID] = it
+			}
+		}
+	}
+	return out, nil
+}
+
+// searchAppNameCandidates returns up to limit App Store search results for appName,
+// ranked by title similarity (see rankAppNameCandidates).
+func searchAppNameCandidates(ctx context.Context, appName, country string, limit int) ([]appNameCandidate, error) {
 	q := url.Values{}
 	q.Set("term", appName)
 	q.Set("entity", "software")
-	q.Set("limit", "10")
+	q.Set("limit", strconv.Itoa(limit))
 	q.Set("country", strings.ToLower(strings.TrimSpace(country)))
 
 	var resp itunesAPIResponse
 	if err := itunesGetJSON(ctx, itunesSearchURL, q, &resp); err != nil {
-		return 0, "", "", err
+		return nil, err
 	}
-	if len(resp.Results) == 0 {
-		return 0, "", "", fmt.Errorf("no App Store result for app-name %q in country %s", appName, strings.ToUpper(country))
+	candidates := rankAppNameCandidates(appName, resp.Results)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no App Store result for app-name %q in country %s", appName, strings.ToUpper(country))
 	}
-
-	target := strings.ToLower(strings.TrimSpace(appName))
-	for _, it := range resp.Results {
-		if it.TrackID <= 0 {
-			continue
-		}
-		if strings.ToLower(strings.TrimSpace(it.TrackName)) == target {
-			return it.TrackID, strings.TrimSpace(it.TrackName), strings.TrimSpace(it.BundleID), nil
-		}
-	}
-
-	for _, it := range resp.Results {
-		if it.TrackID > 0 {
-			return it.TrackID, strings.TrimSpace(it.TrackName), strings.TrimSpace(it.BundleID), nil
-		}
-	}
-
-	return 0, "", "", fmt.Errorf("no valid adam-id found for app-name %q", appName)
+	return candidates, nil
 }
 
 func itunesGetJSON(ctx context.Context, endpoint string, q url.Values, out any) error {
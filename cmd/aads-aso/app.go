@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type asoAppMetadataRow struct {
+	AdamID                    int64    `json:"adamId"`
+	Country                   string   `json:"country"`
+	Name                      string   `json:"name"`
+	Subtitle                  string   `json:"subtitle,omitempty"`
+	BundleID                  string   `json:"bundleId"`
+	Developer                 string   `json:"developer,omitempty"`
+	Seller                    string   `json:"seller,omitempty"`
+	PrimaryGenre              string   `json:"primaryGenre,omitempty"`
+	Genres                    []string `json:"genres,omitempty"`
+	Rating                    float64  `json:"rating"`
+	RatingCount               int64    `json:"ratingCount"`
+	CurrentVersionRating      float64  `json:"currentVersionRating"`
+	CurrentVersionRatingCount int64    `json:"currentVersionRatingCount"`
+	Version                   string   `json:"version,omitempty"`
+	ReleaseDate               string   `json:"releaseDate,omitempty"`
+	CurrentVersionReleaseDate string   `json:"currentVersionReleaseDate,omitempty"`
+	Price                     float64  `json:"price"`
+	FormattedPrice            string   `json:"formattedPrice,omitempty"`
+	Currency                  string   `json:"currency,omitempty"`
+	Languages                 []string `json:"languages,omitempty"`
+	ContentRating             string   `json:"contentRating,omitempty"`
+	MinimumOSVersion          string   `json:"minimumOsVersion,omitempty"`
+	FileSizeBytes             int64    `json:"fileSizeBytes,omitempty"`
+	URL                       string   `json:"url,omitempty"`
+	ReleaseNotes              string   `json:"releaseNotes,omitempty"`
+	Description               string   `json:"description,omitempty"`
+}
+
+// The subtitle is only on the App Store web page: in the server-rendered header of
+// older pages, or in the page's embedded JSON data. The JSON also describes other
+// apps (related apps, the developer's other apps), so only the object with the app's
+// own id is read.
+var (
+	appSubtitleHeaderPattern = regexp.MustCompile(`(?s)<h2[^>]*product-header__subtitle[^>]*>(.*?)</h2>`)
+	appPageScriptPattern     = regexp.MustCompile(`(?s)<script[^>]*>(.*?)</script>`)
+)
+
+func newASOAppCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "app",
+		Short: "Show App Store metadata of one or many apps per storefront (iTunes Lookup)",
+		Long: "Look apps up in every --countries storefront and print one row per app and storefront:\n" +
+			"name, subtitle, developer, seller, genres, ratings, version, release dates, price,\n" +
+			"languages and description. Apps that are not available in a storefront are skipped with a warning.\n" +
+			"The subtitle is read from the App Store web page (--subtitle) and stays empty when it cannot be found.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			countries, err := getCountriesFlag(cmd)
+			if err != nil {
+				return err
+			}
+			ids, err := appIDsFromFlags(ctx, cmd, countries[0])
+			if err != nil {
+				return err
+			}
+			withSubtitle, _ := cmd.Flags().GetBool("subtitle")
+
+			var out []asoAppMetadataRow
+			for _, cc := range countries {
+				found, err := lookupAppsByID(ctx, ids, cc)
+				if err != nil {
+					return fmt.Errorf("lookup apps in %s: %w", cc, err)
+				}
+				for _, id := range ids {
+					it, ok := found[id]
+					if !ok {
+						warnRun("app_not_found", fmt.Sprintf("adam-id %d is not available in storefront %s", id, cc), "adam_id", id, "country", cc)
+						continue
+					}
+					row := appMetadataRow(it, cc)
+					if withSubtitle && row.URL != "" {
+						subtitle, err := fetchAppSubtitle(ctx, row.URL, id)
+						if err != nil {
+							logDebug("subtitle_failed", err.Error(), "adam_id", id, "country", cc)
+						}
+						row.Subtitle = subtitle
+					}
+					out = append(out, row)
+				}
+			}
+			return printOutput(out)
+		},
+	}
+
+	cmd.Flags().String("countries", defaultAdamCountry, "Comma-separated storefronts (alpha-2), e.g. US,GB,DE")
+	cmd.Flags().Int64Slice("adam-id", nil, "App Store adam-ids (comma-separated or repeated)")
+	cmd.Flags().StringArray("app-url", nil, "App Store URL (repeatable)")
+	cmd.Flags().StringSlice("bundle-id", nil, "Bundle IDs, resolved in the first storefront (comma-separated or repeated)")
+	cmd.Flags().StringArray("app-name", nil, "App name, resolved in the first storefront like --app-name of popscore (repeatable)")
+	addMinConfidenceFlag(cmd)
+	cmd.Flags().Bool("subtitle", true, "Read the subtitle from each app's App Store page (one extra request per app and storefront)")
+	return cmd
+}
+
+// appIDsFromFlags collects the adam-ids of all app inputs, in flag order and without
+// duplicates. Bundle IDs and names are resolved in country.
+func appIDsFromFlags(ctx context.Context, cmd *cobra.Command, country string) ([]int64, error) {
+	adamIDs, _ := cmd.Flags().GetInt64Slice("adam-id")
+	appURLs, _ := cmd.Flags().GetStringArray("app-url")
+	bundleIDs, _ := cmd.Flags().GetStringSlice("bundle-id")
+	appNames, _ := cmd.Flags().GetStringArray("app-name")
+	minConfidence, _ := cmd.Flags().GetFloat64("min-confidence")
+
+	var ids []int64
+	seen := map[int64]bool{}
+	add := func(id int64) {
+		if id > 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, id := range adamIDs {
+		add(id)
+	}
+	for _, raw := range appURLs {
+		id, err := parseAdamIDFromAppURL(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse --app-url: %w", err)
+		}
+		add(id)
+	}
+	for _, raw := range bundleIDs {
+		bundleID := strings.TrimSpace(raw)
+		if bundleID == "" {
+			continue
+		}
+		id, _, err := lookupAdamIDByBundleID(ctx, bundleID, country)
+		if err != nil {
+			return nil, fmt.Errorf("resolve --bundle-id %q: %w", bundleID, err)
+		}
+		add(id)
+	}
+	for _, raw := range appNames {
+		appName := strings.TrimSpace(raw)
+		if appName == "" {
+			continue
+		}
+		candidates, err := searchAppNameCandidates(ctx, appName, country, appNameSearchLimit)
+		if err != nil {
+			return nil, fmt.Errorf("resolve --app-name %q: %w", appName, err)
+		}
+		c, err := selectAppNameCandidate(appName, candidates, minConfidence, stdinIsTerminal())
+		if err != nil {
+			return nil, fmt.Errorf("resolve --app-name: %w", err)
+		}
+		add(c.AdamID)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("provide at least one of --adam-id, --app-url, --bundle-id or --app-name")
+	}
+	return ids, nil
+}
+
+func appMetadataRow(it itunesAppEntry, country string) asoAppMetadataRow {
+	size, _ := strconv.ParseInt(strings.TrimSpace(it.FileSizeBytes), 10, 64)
+	return asoAppMetadataRow{
+		AdamID:                    it.TrackID,
+		Country:                   country,
+		Name:                      strings.TrimSpace(it.TrackName),
+		BundleID:                  strings.TrimSpace(it.BundleID),
+		Developer:                 strings.TrimSpace(it.ArtistName),
+		Seller:                    strings.TrimSpace(it.SellerName),
+		PrimaryGenre:              it.PrimaryGenreName,
+		Genres:                    it.Genres,
+		Rating:                    it.AverageUserRating,
+		RatingCount:               it.UserRatingCount,
+		CurrentVersionRating:      it.AverageUserRatingForCurrentVersion,
+		CurrentVersionRatingCount: it.UserRatingCountForCurrentVersion,
+		Version:                   it.Version,
+		ReleaseDate:               it.ReleaseDate,
+		CurrentVersionReleaseDate: it.CurrentVersionReleaseDate,
+		Price:                     it.Price,
+		FormattedPrice:            it.FormattedPrice,
+		Currency:                  it.Currency,
+		Languages:                 it.Languages,
+		ContentRating:             it.ContentRating,
+		MinimumOSVersion:          it.MinimumOSVersion,
+		FileSizeBytes:             size,
+		URL:                       it.TrackViewURL,
+		ReleaseNotes:              strings.TrimSpace(it.ReleaseNotes),
+		Description:               strings.TrimSpace(it.Description),
+	}
+}
+
+// fetchAppSubtitle reads the subtitle from the app's App Store page. A page without
+// one returns "" and no error.
+func fetchAppSubtitle(ctx context.Context, pageURL string, adamID int64) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/html")
+
+	client := &http.Client{Timeout: itunesHTTPTO}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("app store page HTTP %d", resp.StatusCode)
+	}
+	return parseAppSubtitle(string(body), adamID), nil
+}
+
+func parseAppSubtitle(page string, adamID int64) string {
+	if m := appSubtitleHeaderPattern.FindStringSubmatch(page); len(m) == 2 {
+		if sub := strings.TrimSpace(html.UnescapeString(m[1])); sub != "" {
+			return sub
+		}
+	}
+	id := strconv.FormatInt(adamID, 10)
+	for _, m := range appPageScriptPattern.FindAllStringSubmatch(page, -1) {
+		var v any
+		if json.Unmarshal([]byte(strings.TrimSpace(m[1])), &v) != nil {
+			continue
+		}
+		if sub := findAppSubtitle(v, id); sub != "" {
+			return sub
+		}
+	}
+	return ""
+}
+
+// findAppSubtitle looks through decoded page data for the object whose "id" is id
+// and returns the first "subtitle" inside it (directly, under attributes or per
+// platform). JSON documents embedded as strings are decoded too.
+func findAppSubtitle(v any, id string) string {
+	switch t := v.(type) {
+	case map[string]any:
+		if jsonID(t["id"]) == id {
+			if sub := subtitleWithin(t, id); sub != "" {
+				return sub
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(t)) {
+			if sub := findAppSubtitle(t[k], id); sub != "" {
+				return sub
+			}
+		}
+	case []any:
+		for _, child := range t {
+			if sub := findAppSubtitle(child, id); sub != "" {
+				return sub
+			}
+		}
+	case string:
+		if s := strings.TrimSpace(t); strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") {
+			var inner any
+			if json.Unmarshal([]byte(s), &inner) == nil {
+				return findAppSubtitle(inner, id)
+			}
+		}
+	}
+	return ""
+}
+
+// subtitleWithin returns the subtitle of the app object m, without descending into
+// nested objects that describe another resource.
+func subtitleWithin(m map[string]any, id string) string {
+	if sub, ok := m["subtitle"].(string); ok && strings.TrimSpace(sub) != "" {
+		return strings.TrimSpace(html.UnescapeString(sub))
+	}
+	for _, k := range slices.Sorted(maps.Keys(m)) {
+		cm, ok := m[k].(map[string]any)
+		if !ok {
+			continue
+		}
+		if other := jsonID(cm["id"]); other != "" && other != id {
+			continue
+		}
+		if sub := subtitleWithin(cm, id); sub != "" {
+			return sub
+		}
+	}
+	return ""
+}
+
+// jsonID returns a decoded "id" value as text; ids appear both as strings and numbers.
+func jsonID(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseAppSubtitle(t *testing.T) {
+	const adamID = 1234567890
+	shoebox := func(v any) string {
+		inner, _ := json.Marshal(v)
+		outer, _ := json.Marshal(map[string]string{"apps": string(inner)})
+		return `<script type="fastboot/shoebox" id="shoebox-media-api-cache-apps">` + string(outer) + `</script>`
+	}
+
+	tests := []struct {
+		name, page, want string
+	}{
+		{
+			name: "server-rendered header",
+			page: `<h2 class="product-header__subtitle app-header__subtitle">Plants &amp; Trees</h2>`,
+			want: "Plants & Trees",
+		},
+		{
+			name: "own app after related apps",
+			page: `<script type="application/json">{"data": [
+  {"id": "111", "type": "apps", "attributes": {"subtitle": "Someone else's app"}},
+  {"id": "1234567890", "type": "apps", "attributes": {"name": "Mine", "platformAttributes": {"ios": {"subtitle": "Identify plants"}}}}
+]}</script>`,
+			want: "Identify plants",
+		},
+		{
+			name: "numeric id",
+			page: `<script type="application/json">{"d": {"id": 1234567890, "subtitle": "By number"}}</script>`,
+			want: "By number",
+		},
+		{
+			name: "JSON embedded as a string",
+			page: shoebox(map[string]any{"d": []any{map[string]any{"id": "1234567890", "attributes": map[string]any{"subtitle": "From shoebox"}}}}),
+			want: "From shoebox",
+		},
+		{
+			name: "only other apps have a subtitle",
+			page: `<script type="application/json">{"data": [
+  {"id": "1234567890", "attributes": {"name": "Mine", "relationships": {"id": "222", "subtitle": "Nested other app"}}},
+  {"id": "111", "attributes": {"subtitle": "Someone else's app"}}
+]}</script>
+<p>"subtitle": "loose text"</p>`,
+			want: "",
+		},
+		{
+			name: "not JSON",
+			page: `<script>var x = {"id": "1234567890", "subtitle": "in code"};</script>`,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseAppSubtitle(tt.page, adamID); got != tt.want {
+				t.Errorf("parseAppSubtitle = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
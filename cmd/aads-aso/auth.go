@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type asoAuthStatusRow struct {
+	Status       string   `json:"status"`
+	NeedsRefresh bool     `json:"needsRefresh"`
+	Account      string   `json:"account,omitempty"`
+	CookieSource string   `json:"cookieSource"`
+	XSRFToken    bool     `json:"xsrfToken"`
+	Cookies      []string `json:"cookies"`
+	Campaigns    *int     `json:"campaigns,omitempty"`
+	AdamID       int64    `json:"adamId,omitempty"`
+	Expires      string   `json:"expires,omitempty"`
+	Detail       string   `json:"detail,omitempty"`
+}
+
+func newASOAuthCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Inspect the Apple Ads web session used by popscore/recommend",
+	}
+	cmd.AddCommand(newASOAuthStatusCmd())
+	cmd.AddCommand(newASOAuthImportCmd())
+	return cmd
+}
+
+func newASOAuthStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Check whether the stored Apple Ads session cookie still works",
+		Long: "Load the cookie like popscore/recommend do (--cookie-providers: --cookie, --cookie-file, --cookie-exec), make one lightweight\n" +
+			"authenticated call (campaigns/find) and report valid, expired, not-owned, missing or error.\n" +
+			"Exits non-zero unless the session is valid, so scripts can refresh before a batch.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			cookieFile, _ := cmd.Flags().GetString("cookie-file")
+			adamID, _ := cmd.Flags().GetInt64("adam-id")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			extraHeaders, err := getExtraHeaders(cmd)
+			if err != nil {
+				return err
+			}
+
+			row := asoAuthStatusRow{
+				Account: strings.TrimSpace(accountFlag),
+				AdamID:  adamID,
+			}
+			cookies, err := newCookieChainFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			cookie, err := cookies.Cookie(ctx)
+			if err != nil && !errors.Is(err, errNoCookie) {
+				return err
+			}
+			switch cookies.provider {
+			case cookieProviderStatic:
+				row.CookieSource = "--cookie"
+			case cookieProviderExec:
+				row.CookieSource = "--cookie-exec"
+			default:
+				row.CookieSource = cookieFile
+			}
+			row.Cookies = cookieNames(cookie)
+			row.XSRFToken = cookieValue(cookie, "XSRF-TOKEN-CM") != ""
+
+			status, campaigns, err := checkCMSession(ctx, cookie, extraHeaders, timeout)
+			row.Status = status
+			if err != nil {
+				row.Detail = err.Error()
+			}
+			if status == cmSessionValid {
+				n := len(campaigns)
+				row.Campaigns = &n
+				if adamID > 0 && !campaignsIncludeAdamID(campaigns, adamID) {
+					row.Status = cmSessionNotOwned
+					row.Detail = fmt.Sprintf("adam-id %d is not used by any campaign of this account", adamID)
+				}
+			}
+			addDetail := func(s string) {
+				if row.Detail != "" {
+					s = row.Detail + "; " + s
+				}
+				row.Detail = s
+			}
+			if row.Status == cmSessionValid && !row.XSRFToken {
+				addDetail("no XSRF-TOKEN-CM cookie; keyword calls may need --header 'X-XSRF-TOKEN-CM: ...'")
+			}
+			row.NeedsRefresh = row.Status == cmSessionExpired || row.Status == cmSessionMissing
+			if row.CookieSource == cookieFile {
+				if exp, name, ok := storedCookieExpiry(cookieFile); ok {
+					row.Expires = exp.UTC().Format(time.RFC3339)
+					window, _ := cmd.Flags().GetDuration("cookie-expiry-window")
+					if row.Status == cmSessionValid && time.Until(exp) <= window {
+						row.NeedsRefresh = true
+						addDetail(fmt.Sprintf("cookie %s expires within %s", name, window))
+					}
+				}
+			}
+
+			if err := printOutput([]asoAuthStatusRow{row}); err != nil {
+				return err
+			}
+			if row.Status != cmSessionValid {
+				// The report above already explains the problem; skip the usage text.
+				cmd.SilenceUsage = true
+				if row.NeedsRefresh {
+					return fmt.Errorf("session %s; refresh it with: aads-aso cm-cookie --out %s", row.Status, cookieFile)
+				}
+				return fmt.Errorf("session %s: %s", row.Status, row.Detail)
+			}
+			if row.NeedsRefresh {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("session expires soon (%s); refresh it with: aads-aso cm-cookie --out %s", row.Expires, cookieFile)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("cookie", "", "Cookie header value to check instead of --cookie-file")
+	cmd.Flags().String("cookie-file", defaultCMCookieFilePath(), "Path to file containing Cookie header value")
+	addCookieProviderFlags(cmd)
+	cmd.Flags().Int64("adam-id", 0, "Also check that this adam-id belongs to the account (via its campaigns)")
+	cmd.Flags().Duration("timeout", 20*time.Second, "Request timeout")
+	cmd.Flags().Duration("cookie-expiry-window", defaultCookieExpiryWindow, "Report needsRefresh when the stored session expires within this window")
+	addExtraHeaderFlags(cmd)
+	return cmd
+}
+
+// cookieNames lists the cookie names in a Cookie header, in order.
+func cookieNames(cookieHeader string) []string {
+	var names []string
+	for _, part := range strings.Split(cookieHeader, ";") {
+		part = strings.TrimSpace(part)
+		if i := strings.IndexByte(part, '='); i > 0 {
+			names = append(names, strings.TrimSpace(part[:i]))
+		}
+	}
+	return names
+}
+
+func campaignsIncludeAdamID(campaigns []cmCampaignItem, adamID int64) bool {
+	for _, c := range campaigns {
+		if c.AdamID == adamID {
+			return true
+		}
+	}
+	return false
+}
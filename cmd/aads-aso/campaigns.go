@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type asoCampaignRow struct {
+	ID                  int64    `json:"id"`
+	Name                string   `json:"name"`
+	AdamID              int64    `json:"adamId"`
+	Status              string   `json:"status,omitempty"`
+	ServingStatus       string   `json:"servingStatus,omitempty"`
+	DisplayStatus       string   `json:"displayStatus,omitempty"`
+	ServingStateReasons []string `json:"servingStateReasons,omitempty"`
+	Storefronts         []string `json:"storefronts,omitempty"`
+	SupplySources       []string `json:"supplySources,omitempty"`
+	AdChannelType       string   `json:"adChannelType,omitempty"`
+	DailyBudget         string   `json:"dailyBudget,omitempty"`
+	StartTime           string   `json:"startTime,omitempty"`
+	EndTime             string   `json:"endTime,omitempty"`
+}
+
+type asoAppRow struct {
+	AdamID      int64    `json:"adamId"`
+	Name        string   `json:"name,omitempty"`
+	BundleID    string   `json:"bundleId,omitempty"`
+	Country     string   `json:"country,omitempty"` // storefront the App Store details came from
+	Campaigns   int      `json:"campaigns"`
+	Storefronts []string `json:"storefronts,omitempty"`
+}
+
+// cmCampaignDetail is a campaign from campaigns/find with the fields beyond id, name
+// and adamId kept as returned, since the endpoint is undocumented.
+type cmCampaignDetail struct {
+	cmCampaignItem
+	Raw map[string]any
+}
+
+func cmCampaignsFindDetailed(ctx context.Context, cookie string, extraHeaders map[string]string) ([]cmCampaignDetail, error) {
+	b, err := cmGetJSON(ctx, cmAPIBase+"/campaigns/find", cookie, extraHeaders)
+	if err != nil {
+		return nil, err
+	}
+	items, err := parseCMCampaignData("campaigns/find", b)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Data []map[string]any `json:"data"`
+	}
+	_ = json.Unmarshal(b, &raw)
+
+	out := make([]cmCampaignDetail, 0, len(items))
+	for i, it := range items {
+		d := cmCampaignDetail{cmCampaignItem: it}
+		if i < len(raw.Data) {
+			d.Raw = raw.Data[i]
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// cmAdGroupItem is one ad group of a campaign (adgroups/find).
+type cmAdGroupItem struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	CampaignID int64  `json:"campaignId"`
+}
+
+// cmAdGroupsFind lists the ad groups of one campaign.
+func cmAdGroupsFind(ctx context.Context, cookie string, extraHeaders map[string]string, campaignID int64) ([]cmAdGroupItem, error) {
+	endpoint := fmt.Sprintf("campaigns/%d/adgroups/find", campaignID)
+	b, err := cmGetJSON(ctx, cmAPIBase+"/"+endpoint, cookie, extraHeaders)
+	if err != nil {
+		return nil, err
+	}
+	var ok struct {
+		Status string          `json:"status"`
+		Data   []cmAdGroupItem `json:"data"`
+	}
+	if err := json.Unmarshal(b, &ok); err == nil && (ok.Status == "" || strings.EqualFold(ok.Status, "success")) {
+		return ok.Data, nil
+	}
+	return nil, cmResponseError(endpoint, b)
+}
+
+// fetchCMCampaignsFromFlags lists the account's campaigns with the cookie from the
+// cookie providers, refreshing it once when Apple rejects it.
+func fetchCMCampaignsFromFlags(ctx context.Context, cmd *cobra.Command) ([]cmCampaignDetail, error) {
+	cookies, err := newCookieChainFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cookie, err := cookies.Cookie(ctx)
+	if err != nil {
+		return nil, err
+	}
+	extraHeaders, err := getExtraHeaders(cmd)
+	if err != nil {
+		return nil, err
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+
+	find := func() ([]cmCampaignDetail, error) {
+		reqCtx, cancel := withOptionalTimeout(ctx, timeout)
+		defer cancel()
+		return cmCampaignsFindDetailed(reqCtx, cookie, extraHeaders)
+	}
+	campaigns, err := find()
+	if err != nil && cookies.canRefresh() && isCMRefreshError(err) {
+		if cookie, err = cookies.Refresh(ctx); err != nil {
+			return nil, err
+		}
+		campaigns, err = find()
+	}
+	return campaigns, err
+}
+
+func newASOCampaignsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "campaigns",
+		Short: "List the Apple Ads campaigns of the account (id, name, adam-id, status, storefronts)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			campaigns, err := fetchCMCampaignsFromFlags(cmd.Context(), cmd)
+			if err != nil {
+				return err
+			}
+			adamID, _ := cmd.Flags().GetInt64("adam-id")
+
+			out := make([]asoCampaignRow, 0, len(campaigns))
+			for _, c := range campaigns {
+				if adamID > 0 && c.AdamID != adamID {
+					continue
+				}
+				out = append(out, asoCampaignRow{
+					ID:                  c.ID,
+					Name:                c.Name,
+					AdamID:              c.AdamID,
+					Status:              rawString(c.Raw, "status"),
+					ServingStatus:       rawString(c.Raw, "servingStatus"),
+					DisplayStatus:       rawString(c.Raw, "displayStatus"),
+					ServingStateReasons: rawStrings(c.Raw, "servingStateReasons"),
+					Storefronts:         campaignStorefronts(c.Raw),
+					SupplySources:       rawStrings(c.Raw, "supplySources"),
+					AdChannelType:       rawString(c.Raw, "adChannelType"),
+					DailyBudget:         rawMoney(c.Raw, "dailyBudgetAmount"),
+					StartTime:           rawString(c.Raw, "startTime"),
+					EndTime:             rawString(c.Raw, "endTime"),
+				})
+			}
+			return printOutput(out)
+		},
+	}
+
+	cmd.Flags().Int64("adam-id", 0, "Only list campaigns for this adam-id")
+	cmd.Flags().Duration("timeout", 30*time.Second, "Request timeout")
+	addCookieFlags(cmd, true)
+	addExtraHeaderFlags(cmd)
+	return cmd
+}
+
+func newASOAppsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apps",
+		Short: "List the apps (adam-ids) the account advertises, with App Store name and bundle ID",
+		Long: "List the distinct adam-ids used by the account's campaigns, enriched via iTunes Lookup.\n" +
+			"Apps are looked up in --country first, then in their campaigns' storefronts.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			campaigns, err := fetchCMCampaignsFromFlags(ctx, cmd)
+			if err != nil {
+				return err
+			}
+			country, _ := cmd.Flags().GetString("country")
+			country = strings.ToUpper(strings.TrimSpace(country))
+			if country == "" {
+				country = defaultAdamCountry
+			}
+
+			var out []asoAppRow
+			index := map[int64]int{}
+			for _, c := range campaigns {
+				if c.AdamID <= 0 {
+					continue
+				}
+				i, seen := index[c.AdamID]
+				if !seen {
+					i = len(out)
+					index[c.AdamID] = i
+					out = append(out, asoAppRow{AdamID: c.AdamID})
+				}
+				out[i].Campaigns++
+				for _, cc := range campaignStorefronts(c.Raw) {
+					if !containsFold(out[i].Storefronts, cc) {
+						out[i].Storefronts = append(out[i].Storefronts, cc)
+					}
+				}
+			}
+			for i := range out {
+				sort.Strings(out[i].Storefronts)
+			}
+
+			if err := enrichAppRows(ctx, out, country); err != nil {
+				warnRun("app_lookup_failed", "iTunes Lookup failed; listing apps without name and bundle ID: "+err.Error())
+			}
+			return printOutput(out)
+		},
+	}
+
+	cmd.Flags().String("country", defaultAdamCountry, "Storefront used for the App Store lookup")
+	cmd.Flags().Duration("timeout", 30*time.Second, "Request timeout")
+	addCookieFlags(cmd, true)
+	addExtraHeaderFlags(cmd)
+	return cmd
+}
+
+// enrichAppRows fills name and bundle ID from iTunes Lookup, trying country and then
+// each app's own storefronts for apps that are not available in country.
+func enrichAppRows(ctx context.Context, rows []asoAppRow, country string) error {
+	tried := map[string]bool{}
+	try := func(cc string, ids []int64) error {
+		if len(ids) == 0 || tried[cc] {
+			return nil
+		}
+		tried[cc] = true
+		found, err := lookupAppsByID(ctx, ids, cc)
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			if it, ok := found[rows[i].AdamID]; ok && rows[i].Name == "" {
+				rows[i].Name = strings.TrimSpace(it.TrackName)
+				rows[i].BundleID = strings.TrimSpace(it.BundleID)
+				rows[i].Country = cc
+			}
+		}
+		return nil
+	}
+
+	ids := make([]int64, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.AdamID)
+	}
+	if err := try(country, ids); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if r.Name != "" {
+			continue
+		}
+		for _, cc := range r.Storefronts {
+			if err := try(cc, []int64{r.AdamID}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// campaignStorefronts reads the campaign's countries/regions, whichever key the
+// endpoint uses.
+func campaignStorefronts(raw map[string]any) []string {
+	for _, key := range []string{"countriesOrRegions", "storefronts", "countryOrRegion"} {
+		if v := rawStrings(raw, key); len(v) > 0 {
+			return v
+		}
+	}
+	return nil
+}
+
+func rawString(raw map[string]any, key string) string {
+	return rawValueString(raw[key])
+}
+
+// rawValueString formats a decoded JSON value. Numbers are written out in full, so
+// large IDs and amounts do not turn into exponent form.
+func rawValueString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+func rawStrings(raw map[string]any, key string) []string {
+	switch v := raw[key].(type) {
+	case string:
+		if s := strings.TrimSpace(v); s != "" {
+			return []string{s}
+		}
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, it := range v {
+			if s := rawValueString(it); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// rawMoney formats {"amount": "10", "currency": "USD"} as "10 USD".
+func rawMoney(raw map[string]any, key string) string {
+	m, ok := raw[key].(map[string]any)
+	if !ok {
+		return rawString(raw, key)
+	}
+	return strings.TrimSpace(rawString(m, "amount") + " " + rawString(m, "currency"))
+}
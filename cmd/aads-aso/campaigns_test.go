@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRawValues(t *testing.T) {
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(`{
+  "id": 1234567890,
+  "big": 12345678901234,
+  "ratio": 0.25,
+  "paused": false,
+  "name": "  Brand  ",
+  "nothing": null,
+  "obj": {"a": 1},
+  "budget": {"amount": 1500000000, "currency": "USD"},
+  "cap": "10",
+  "countries": ["US", 2000000000, null, " "]
+}`), &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		key, want string
+	}{
+		{"id", "1234567890"},
+		{"big", "12345678901234"},
+		{"ratio", "0.25"},
+		{"paused", "false"},
+		{"name", "Brand"},
+		{"nothing", ""},
+		{"missing", ""},
+		{"obj", `{"a":1}`},
+	}
+	for _, tt := range tests {
+		if got := rawString(raw, tt.key); got != tt.want {
+			t.Errorf("rawString(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+	if got := rawMoney(raw, "budget"); got != "1500000000 USD" {
+		t.Errorf("rawMoney(budget) = %q", got)
+	}
+	if got := rawMoney(raw, "cap"); got != "10" {
+		t.Errorf("rawMoney(cap) = %q", got)
+	}
+	if got := rawStrings(raw, "countries"); !reflect.DeepEqual(got, []string{"US", "2000000000"}) {
+		t.Errorf("rawStrings(countries) = %q", got)
+	}
+}
@@ -0,0 +1,588 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Browser drivers used to refresh the Apple Ads session cookie.
+const (
+	browserDriverPlaywright = "playwright"
+	browserDriverCDP        = "cdp"
+)
+
+const cdpStartupTimeout = 30 * time.Second
+
+func addBrowserDriverFlags(cmd *cobra.Command) {
+	cmd.Flags().String("browser-driver", browserDriverPlaywright, "Browser automation for cookie refresh: playwright (npx) or cdp (local Chrome/Chromium, no Node.js)")
+	cmd.Flags().String("browser-path", "", "Chrome/Chromium executable for --browser-driver cdp (defaults to the first one found)")
+}
+
+func getBrowserDriverFlags(cmd *cobra.Command) (driver, browserPath string, err error) {
+	driver, _ = cmd.Flags().GetString("browser-driver")
+	browserPath, _ = cmd.Flags().GetString("browser-path")
+	driver = strings.ToLower(strings.TrimSpace(driver))
+	switch driver {
+	case "", browserDriverPlaywright:
+		driver = browserDriverPlaywright
+	case browserDriverCDP:
+	default:
+		return "", "", fmt.Errorf("invalid --browser-driver %q (expected playwright or cdp)", driver)
+	}
+	return driver, expandHome(strings.TrimSpace(browserPath)), nil
+}
+
+// findChromeExecutable returns browserPath when set, otherwise the first installed
+// Chrome, Chromium or Edge.
+func findChromeExecutable(browserPath string) (string, error) {
+	if browserPath != "" {
+		if p, err := exec.LookPath(browserPath); err == nil {
+			return p, nil
+		}
+		return "", fmt.Errorf("browser %s not found", browserPath)
+	}
+
+	var candidates []string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = []string{
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+			"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+		}
+	case "windows":
+		for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)", "LocalAppData"} {
+			if dir := os.Getenv(env); dir != "" {
+				candidates = append(candidates,
+					filepath.Join(dir, "Google", "Chrome", "Application", "chrome.exe"),
+					filepath.Join(dir, "Microsoft", "Edge", "Application", "msedge.exe"))
+			}
+		}
+	}
+	for _, p := range candidates {
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p, nil
+		}
+	}
+	for _, name := range []string{"google-chrome", "google-chrome-stable", "chromium", "chromium-browser", "chrome", "microsoft-edge"} {
+		if p, err := exec.LookPath(name); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("no Chrome/Chromium found; install one, pass --browser-path, or use --browser-driver playwright")
+}
+
+// refreshCMCookieViaCDP launches a local Chrome with the persistent profile and the
+// DevTools protocol enabled, waits for login and reads the cookies with
+// Network.getCookies. Unlike the Playwright driver it needs no Node.js.
+func refreshCMCookieViaCDP(ctx context.Context, opts cmCookieRefreshOptions, url, profileDir string) (string, error) {
+	bin, err := findChromeExecutable(opts.BrowserPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Chrome writes the chosen port to DevToolsActivePort; drop a stale one first.
+	portFile := filepath.Join(profileDir, "DevToolsActivePort")
+	_ = os.Remove(portFile)
+
+	args := []string{
+		"--user-data-dir=" + profileDir,
+		"--remote-debugging-port=0",
+		"--no-first-run",
+		"--no-default-browser-check",
+	}
+	if !opts.Headed {
+		args = append(args, "--headless=new")
+	}
+	args = append(args, url)
+
+	// A cancelled refresh kills the browser instead of leaving it running.
+	browser := exec.CommandContext(ctx, bin, args...)
+	if err := browser.Start(); err != nil {
+		return "", fmt.Errorf("start browser: %w", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		_ = browser.Wait()
+		close(exited)
+	}()
+	closed := false
+	defer func() {
+		if !closed && opts.CloseBrowser {
+			_ = browser.Process.Kill()
+		}
+	}()
+
+	port, browserWSPath, err := waitForDevToolsPort(ctx, portFile, exited)
+	if err != nil {
+		return "", err
+	}
+	logDebug("cdp_ready", "Browser DevTools endpoint is ready", "browser", bin, "port", port)
+
+	// Page connections survive navigation, so one is enough for the whole login.
+	pageCtx, cancel := withOptionalTimeout(ctx, cdpStartupTimeout)
+	page, err := dialCDPPage(pageCtx, port)
+	cancel()
+	if err != nil {
+		return "", err
+	}
+	defer page.Close()
+	probe := func(ctx context.Context) (cmLoginState, error) {
+		return cdpLoginProbe(ctx, page)
+	}
+
+	var cookies []exportedCookie
+	if opts.LoginMode == loginModePoll {
+		if cookies, err = waitForCMLogin(ctx, opts, probe); err != nil {
+			return "", err
+		}
+	} else {
+		promptForLogin(url)
+		extractCtx, cancel := withOptionalTimeout(ctx, opts.Timeout)
+		defer cancel()
+		state, err := probe(extractCtx)
+		if err != nil {
+			return "", err
+		}
+		cookies = state.Cookies
+	}
+	navigate := func(ctx context.Context, url string) error {
+		return cdpNavigate(ctx, page, url)
+	}
+	cookies = warmUpCMSession(ctx, opts, cookies, navigate, probe)
+	cookieHeader := buildCMCookieHeader(cookies, time.Now())
+	if cookieHeader == "" {
+		return "", fmt.Errorf("exported cookie is empty; are you logged in to app-ads.apple.com in the opened browser?")
+	}
+
+	if opts.CloseBrowser {
+		closed = true
+		closeChromeBrowser(ctx, browser, port, browserWSPath, exited)
+	}
+
+	if strings.TrimSpace(opts.OutPath) != "" {
+		if err := saveCookieFile(opts.OutPath, cookieHeader, browserDriverCDP, cookies); err != nil {
+			return "", err
+		}
+	}
+	return cookieHeader, nil
+}
+
+// waitForDevToolsPort reads "PORT\n/devtools/browser/ID" from DevToolsActivePort.
+func waitForDevToolsPort(ctx context.Context, portFile string, exited <-chan struct{}) (int, string, error) {
+	deadline := time.NewTimer(cdpStartupTimeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(100 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		if b, err := os.ReadFile(portFile); err == nil {
+			lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+			if len(lines) == 2 {
+				if port, err := strconv.Atoi(strings.TrimSpace(lines[0])); err == nil && port > 0 {
+					return port, strings.TrimSpace(lines[1]), nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return 0, "", ctx.Err()
+		case <-exited:
+			// Chrome hands the URL to an already running instance and exits when the
+			// profile is in use.
+			return 0, "", fmt.Errorf("browser exited before DevTools was ready; is the profile %s already open in another browser?", filepath.Dir(portFile))
+		case <-deadline.C:
+			return 0, "", fmt.Errorf("browser did not open a DevTools port within %s", cdpStartupTimeout)
+		case <-tick.C:
+		}
+	}
+}
+
+type cdpTarget struct {
+	Type                 string `json:"type"`
+	URL                  string `json:"url"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// dialCDPPage connects to the first open tab.
+func dialCDPPage(ctx context.Context, port int) (*cdpConn, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/json/list", port), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("list DevTools targets: %w", err)
+	}
+	defer resp.Body.Close()
+	var targets []cdpTarget
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("list DevTools targets: %w", err)
+	}
+	for _, t := range targets {
+		if t.Type == "page" && t.WebSocketDebuggerURL != "" {
+			return dialCDP(ctx, t.WebSocketDebuggerURL)
+		}
+	}
+	return nil, fmt.Errorf("no open browser tab to read cookies from")
+}
+
+// cdpLoginProbe reads the cookies the browser would send to the keyword API and
+// whether an Apple sign-in frame is showing. The sign-in frame is cross-origin, so
+// unlike the Playwright probe it cannot tell a two-factor prompt apart.
+func cdpLoginProbe(ctx context.Context, page *cdpConn) (cmLoginState, error) {
+	var cookies struct {
+		Cookies []any `json:"cookies"`
+	}
+	cookieURL := "https://" + cmCookieHost + cmCookiePath
+	if err := page.call(ctx, "Network.getCookies", map[string]any{"urls": []string{cookieURL}}, &cookies); err != nil {
+		return cmLoginState{}, err
+	}
+
+	var eval struct {
+		Result struct {
+			Value string `json:"value"`
+		} `json:"result"`
+	}
+	expr := "JSON.stringify({url: location.href, frames: Array.from(document.querySelectorAll('iframe')).map(f => f.src)})"
+	if err := page.call(ctx, "Runtime.evaluate", map[string]any{"expression": expr, "returnByValue": true}, &eval); err != nil {
+		return cmLoginState{}, err
+	}
+	var doc struct {
+		URL    string   `json:"url"`
+		Frames []string `json:"frames"`
+	}
+	_ = json.Unmarshal([]byte(eval.Result.Value), &doc)
+
+	state := cmLoginState{URL: doc.URL, Cookies: parseJSONCookies(cookies.Cookies)}
+	for _, u := range append([]string{doc.URL}, doc.Frames...) {
+		if isAppleSignInURL(u) {
+			state.SignIn = true
+		}
+	}
+	return state, nil
+}
+
+// cdpNavigate opens url in the page and waits until it has loaded and settled.
+func cdpNavigate(ctx context.Context, page *cdpConn, url string) error {
+	var nav struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := page.call(ctx, "Page.navigate", map[string]any{"url": url}, &nav); err != nil {
+		return err
+	}
+	if nav.ErrorText != "" {
+		return fmt.Errorf("open %s: %s", url, nav.ErrorText)
+	}
+
+	// The old document may still report "complete" right after navigating.
+	wait := 500 * time.Millisecond
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		var eval struct {
+			Result struct {
+				Value string `json:"value"`
+			} `json:"result"`
+		}
+		if err := page.call(ctx, "Runtime.evaluate", map[string]any{"expression": "document.readyState", "returnByValue": true}, &eval); err != nil {
+			return err
+		}
+		if eval.Result.Value == "complete" {
+			break
+		}
+		wait = 250 * time.Millisecond
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(warmUpSettle):
+		return nil
+	}
+}
+
+func isAppleSignInURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == "idmsa.apple.com" || host == "appleid.apple.com"
+}
+
+// closeChromeBrowser asks the browser to close so the profile is flushed to disk,
+// and kills it if it does not exit in time.
+func closeChromeBrowser(ctx context.Context, browser *exec.Cmd, port int, browserWSPath string, exited <-chan struct{}) {
+	closeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if conn, err := dialCDP(closeCtx, fmt.Sprintf("ws://127.0.0.1:%d%s", port, browserWSPath)); err == nil {
+		_ = conn.call(closeCtx, "Browser.close", nil, nil)
+		conn.Close()
+	}
+	select {
+	case <-exited:
+	case <-time.After(10 * time.Second):
+		_ = browser.Process.Kill()
+	}
+}
+
+// cdpConn is a minimal WebSocket client for the DevTools protocol: text frames,
+// client masking, and request/response matching by id. Events are skipped.
+type cdpConn struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	nextID int
+	mu     sync.Mutex
+	// broken is set once a read or write failed; the connection is closed then and
+	// every later call returns this error, so callers have to redial.
+	broken error
+}
+
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+func dialCDP(ctx context.Context, wsURL string) (*cdpConn, error) {
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DevTools URL %q: %w", wsURL, err)
+	}
+	if u.Scheme != "ws" {
+		return nil, fmt.Errorf("unsupported DevTools URL %q (expected ws://)", wsURL)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("connect to DevTools: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	handshake := "GET " + u.RequestURI() + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := io.WriteString(conn, handshake); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("DevTools handshake: %w", err)
+	}
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("DevTools handshake: %w", err)
+	}
+	resp.Body.Close()
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != base64.StdEncoding.EncodeToString(sum[:]) {
+		conn.Close()
+		return nil, fmt.Errorf("DevTools handshake rejected: %s", resp.Status)
+	}
+	// ctx only bounds the handshake; call applies each command's own context.
+	_ = conn.SetDeadline(time.Time{})
+	return &cdpConn{conn: conn, r: r}, nil
+}
+
+func (c *cdpConn) Close() error {
+	return c.conn.Close()
+}
+
+// call sends one command and decodes its result into out (which may be nil).
+func (c *cdpConn) call(ctx context.Context, method string, params any, out any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.broken != nil {
+		return fmt.Errorf("%s: %w", method, c.broken)
+	}
+
+	interrupted := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetDeadline(time.Now())
+		close(interrupted)
+	})
+	defer func() {
+		// ctx ended after the response was read: clear the deadline for the next call.
+		if !stop() && c.broken == nil {
+			<-interrupted
+			_ = c.conn.SetDeadline(time.Time{})
+		}
+	}()
+
+	c.nextID++
+	id := c.nextID
+	msg := map[string]any{"id": id, "method": method}
+	if params != nil {
+		msg["params"] = params
+	}
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := c.writeFrame(0x1, b); err != nil {
+		return c.fail(ctx, method, err)
+	}
+
+	for {
+		payload, err := c.readMessage()
+		if err != nil {
+			return c.fail(ctx, method, err)
+		}
+		var resp struct {
+			ID     int             `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(payload, &resp); err != nil {
+			return fmt.Errorf("%s: parse response: %w", method, err)
+		}
+		if resp.ID != id {
+			continue // event or stale response
+		}
+		if resp.Error != nil {
+			return fmt.Errorf("%s: %s (code %d)", method, resp.Error.Message, resp.Error.Code)
+		}
+		if out == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return fmt.Errorf("%s: parse result: %w", method, err)
+		}
+		return nil
+	}
+}
+
+// fail closes the connection after a read or write error. A cancelled call can stop
+// in the middle of a frame, and the deadline that interrupted it stays set, so the
+// stream cannot be used again.
+func (c *cdpConn) fail(ctx context.Context, method string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	c.broken = fmt.Errorf("DevTools connection unusable after an earlier error: %w", err)
+	_ = c.conn.Close()
+	return fmt.Errorf("%s: %w", method, err)
+}
+
+// writeFrame writes a single masked frame, as clients must.
+func (c *cdpConn) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xffff:
+		header = append(header, 0x80|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 0x80|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	header = append(header, mask[:]...)
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+	_, err := c.conn.Write(append(header, masked...))
+	return err
+}
+
+// readMessage returns the next text or binary message, joining fragments and
+// answering pings.
+func (c *cdpConn) readMessage() ([]byte, error) {
+	var message []byte
+	for {
+		var head [2]byte
+		if _, err := io.ReadFull(c.r, head[:]); err != nil {
+			return nil, err
+		}
+		fin, opcode := head[0]&0x80 != 0, head[0]&0x0f
+		masked, n := head[1]&0x80 != 0, uint64(head[1]&0x7f)
+		switch n {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+				return nil, err
+			}
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(c.r, ext[:]); err != nil {
+				return nil, err
+			}
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+		if n > 256<<20 {
+			return nil, fmt.Errorf("DevTools message too large (%d bytes)", n)
+		}
+		var mask [4]byte
+		if masked {
+			if _, err := io.ReadFull(c.r, mask[:]); err != nil {
+				return nil, err
+			}
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(c.r, payload); err != nil {
+			return nil, err
+		}
+		if masked {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+
+		switch opcode {
+		case 0x8:
+			return nil, errors.New("DevTools connection closed")
+		case 0x9:
+			if err := c.writeFrame(0xA, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case 0xA:
+			continue
+		}
+		message = append(message, payload...)
+		if fin {
+			return message, nil
+		}
+	}
+}
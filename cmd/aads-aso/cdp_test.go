@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsFrame is one WebSocket frame as seen on the wire.
+type wsFrame struct {
+	fin     bool
+	opcode  byte
+	masked  bool
+	payload []byte // unmasked
+}
+
+// readWSFrame reads one frame and unmasks its payload.
+func readWSFrame(r io.Reader) (wsFrame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return wsFrame{}, err
+	}
+	f := wsFrame{fin: head[0]&0x80 != 0, opcode: head[0] & 0x0f, masked: head[1]&0x80 != 0}
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return wsFrame{}, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return wsFrame{}, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	var mask [4]byte
+	if f.masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return wsFrame{}, err
+		}
+	}
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return wsFrame{}, err
+	}
+	if f.masked {
+		for i := range f.payload {
+			f.payload[i] ^= mask[i%4]
+		}
+	}
+	return f, nil
+}
+
+// writeWSFrame writes an unmasked server frame.
+func writeWSFrame(w io.Writer, fin bool, opcode byte, payload []byte) error {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	header := []byte{b0}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xffff:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+// newWSServer serves one WebSocket connection per request and hands it to script
+// after the handshake. accept overrides the Sec-WebSocket-Accept value when set.
+func newWSServer(t *testing.T, accept string, script func(t *testing.T, rw *bufio.ReadWriter)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if r.Header.Get("Upgrade") != "websocket" || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+			http.Error(w, "not a websocket handshake", http.StatusBadRequest)
+			return
+		}
+		if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != 16 {
+			http.Error(w, "bad key", http.StatusBadRequest)
+			return
+		}
+		if accept == "" {
+			sum := sha1.Sum([]byte(key + webSocketGUID))
+			accept = base64.StdEncoding.EncodeToString(sum[:])
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", accept)
+		if err := rw.Flush(); err != nil {
+			t.Error(err)
+			return
+		}
+		if script != nil {
+			script(t, rw)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func wsURL(srv *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/devtools/page/1"
+}
+
+// readCDPCommand reads one client frame, checks that it is a masked final text frame
+// and decodes the command.
+func readCDPCommand(t *testing.T, rw *bufio.ReadWriter) (id int, method string) {
+	t.Helper()
+	f, err := readWSFrame(rw)
+	if err != nil {
+		t.Errorf("read command: %v", err)
+		return 0, ""
+	}
+	if !f.masked || !f.fin || f.opcode != 0x1 {
+		t.Errorf("command frame: masked=%v fin=%v opcode=%#x, want a masked final text frame", f.masked, f.fin, f.opcode)
+	}
+	var msg struct {
+		ID     int    `json:"id"`
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(f.payload, &msg); err != nil {
+		t.Errorf("decode command %q: %v", f.payload, err)
+	}
+	return msg.ID, msg.Method
+}
+
+func TestDialCDPHandshake(t *testing.T) {
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+
+	tests := []struct {
+		name    string
+		url     func(*httptest.Server) string
+		accept  string
+		wantErr string
+	}{
+		{"valid", wsURL, "", ""},
+		{"wrong accept", wsURL, "bm90IHRoZSByaWdodCBrZXk=", "handshake rejected"},
+		{"not upgraded", func(*httptest.Server) string { return wsURL(plain) }, "", "handshake rejected: 404"},
+		{"wss", func(*httptest.Server) string { return "wss://127.0.0.1:1/x" }, "", "expected ws://"},
+		{"bad url", func(*httptest.Server) string { return "ws://%zz" }, "", "invalid DevTools URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newWSServer(t, tt.accept, nil)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := dialCDP(ctx, tt.url(srv))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				conn.Close()
+				return
+			}
+			if err == nil {
+				conn.Close()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCDPCall(t *testing.T) {
+	big := strings.Repeat("x", 70000) // needs the 64-bit length
+	srv := newWSServer(t, "", func(t *testing.T, rw *bufio.ReadWriter) {
+		// 1: an event and a stale response first, then the answer in three fragments
+		// with a ping in between.
+		id, method := readCDPCommand(t, rw)
+		if method != "Runtime.evaluate" {
+			t.Errorf("method = %q", method)
+		}
+		resp := fmt.Sprintf(`{"id":%d,"result":{"value":"%s"}}`, id, big)
+		_ = writeWSFrame(rw, true, 0x1, []byte(`{"method":"Page.loadEventFired","params":{}}`))
+		_ = writeWSFrame(rw, true, 0x1, []byte(`{"id":999,"result":{}}`))
+		_ = writeWSFrame(rw, false, 0x1, []byte(resp[:10]))
+		_ = writeWSFrame(rw, true, 0x9, []byte("hi"))
+		_ = writeWSFrame(rw, false, 0x0, []byte(resp[10:300]))
+		_ = writeWSFrame(rw, true, 0x0, []byte(resp[300:]))
+		_ = rw.Flush()
+		pong, err := readWSFrame(rw)
+		if err != nil || pong.opcode != 0xA || !pong.masked || string(pong.payload) != "hi" {
+			t.Errorf("pong = %+v, %v; want a masked pong echoing the ping", pong, err)
+		}
+
+		// 2: a protocol error.
+		id, _ = readCDPCommand(t, rw)
+		_ = writeWSFrame(rw, true, 0xA, nil) // unsolicited pong is ignored
+		_ = writeWSFrame(rw, true, 0x1, []byte(fmt.Sprintf(`{"id":%d,"error":{"code":-32000,"message":"No target"}}`, id)))
+		_ = rw.Flush()
+
+		// 3: the server closes the connection.
+		readCDPCommand(t, rw)
+		_ = writeWSFrame(rw, true, 0x8, nil)
+		_ = rw.Flush()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := dialCDP(ctx, wsURL(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := conn.call(ctx, "Runtime.evaluate", map[string]any{"expression": "1"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Value != big {
+		t.Errorf("value has %d bytes, want %d", len(out.Value), len(big))
+	}
+
+	if err := conn.call(ctx, "Target.attach", nil, nil); err == nil || !strings.Contains(err.Error(), "No target (code -32000)") {
+		t.Errorf("protocol error: err = %v", err)
+	}
+	if err := conn.call(ctx, "Browser.close", nil, nil); err == nil || !strings.Contains(err.Error(), "connection closed") {
+		t.Errorf("close frame: err = %v", err)
+	}
+}
+
+func TestCDPCallContext(t *testing.T) {
+	// The server never answers; the call must return when ctx is done.
+	srv := newWSServer(t, "", func(t *testing.T, rw *bufio.ReadWriter) {
+		_, _ = io.Copy(io.Discard, rw)
+	})
+	conn, err := dialCDP(context.Background(), wsURL(srv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := conn.call(ctx, "Runtime.evaluate", nil, nil); err == nil || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("err = %v, want a deadline error", err)
+	}
+
+	// The cancelled call may have stopped mid-frame: later calls fail at once instead
+	// of reading a desynced stream or hitting the stale deadline.
+	start := time.Now()
+	err = conn.call(context.Background(), "Runtime.evaluate", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "connection unusable") || !strings.Contains(err.Error(), context.DeadlineExceeded.Error()) {
+		t.Errorf("call after cancel: err = %v, want the connection to be marked unusable", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("call after cancel took %s", time.Since(start))
+	}
+}
+
+func TestCDPWriteFrame(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 0xffff, 0x10000} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+			c := &cdpConn{conn: client}
+
+			payload := bytes.Repeat([]byte{'a'}, n)
+			done := make(chan error, 1)
+			go func() { done <- c.writeFrame(0x1, payload) }()
+
+			var raw bytes.Buffer
+			f, err := readWSFrame(io.TeeReader(server, &raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+			if !f.fin || f.opcode != 0x1 || !f.masked || !bytes.Equal(f.payload, payload) {
+				t.Errorf("frame fin=%v opcode=%#x masked=%v len=%d", f.fin, f.opcode, f.masked, len(f.payload))
+			}
+			wantHeader := 2 + 4
+			switch {
+			case n > 0xffff:
+				wantHeader += 8
+			case n > 125:
+				wantHeader += 2
+			}
+			if got := raw.Len() - n; got != wantHeader {
+				t.Errorf("header is %d bytes, want %d", got, wantHeader)
+			}
+			if n > 0 && bytes.Equal(raw.Bytes()[wantHeader:], payload) {
+				t.Error("payload is not masked on the wire")
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type asoClusterRow struct {
+	Country           string   `json:"country,omitempty"`
+	Cluster           int      `json:"cluster"`
+	Head              string   `json:"head"`
+	Size              int      `json:"size"`
+	TotalPopularity   int      `json:"totalPopularity"`
+	AveragePopularity float64  `json:"averagePopularity"`
+	SharedStems       []string `json:"sharedStems,omitempty"`
+	Terms             []string `json:"terms"`
+}
+
+// clusterTerm is one keyword read back from a previous command output.
+type clusterTerm struct {
+	Term       string
+	Country    string
+	Popularity *int
+
+	stems    []string
+	trigrams map[string]bool
+}
+
+func newASOClusterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cluster [FILE...]",
+		Short: "Group keywords from previous JSON output by shared stems and n-gram overlap",
+		Long: "Group keywords by shared stems/tokens and character trigram overlap.\n" +
+			"Reads the JSON output of popscore, recommend, hints or discover from the given files (or stdin).\n" +
+			"Terms are visited by descending popularity; each joins the first cluster whose head is similar enough, otherwise it becomes a new head.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			lang, _ := cmd.Flags().GetString("lang")
+			lang = strings.ToLower(strings.TrimSpace(lang))
+			if lang == "" {
+				lang = "auto"
+			}
+			if lang != "auto" && lang != "none" {
+				if _, ok := stemRulesByLang[lang]; !ok {
+					return fmt.Errorf("unsupported --lang %q (expected auto, none, en, de, fr, es)", lang)
+				}
+			}
+
+			threshold, _ := cmd.Flags().GetFloat64("threshold")
+			if threshold <= 0 || threshold > 1 {
+				return fmt.Errorf("--threshold must be in (0, 1]")
+			}
+			minSize, _ := cmd.Flags().GetInt("min-size")
+			byCountry, _ := cmd.Flags().GetBool("by-country")
+
+			if len(args) == 0 {
+				args = []string{"-"}
+			}
+			var terms []clusterTerm
+			for _, path := range args {
+				data, err := readInputFile(path)
+				if err != nil {
+					return err
+				}
+				parsed, err := parseClusterTerms(data)
+				if err != nil {
+					return fmt.Errorf("read %s: %w", path, err)
+				}
+				terms = append(terms, parsed...)
+			}
+			if len(terms) == 0 {
+				return fmt.Errorf("no keywords found in input (expected rows with a term or keyword field)")
+			}
+
+			groups := map[string][]clusterTerm{}
+			var countries []string
+			for _, t := range terms {
+				key := ""
+				if byCountry {
+					key = t.Country
+				}
+				if _, ok := groups[key]; !ok {
+					countries = append(countries, key)
+				}
+				groups[key] = append(groups[key], t)
+			}
+
+			var out []asoClusterRow
+			for _, cc := range countries {
+				termLang := lang
+				if termLang == "auto" {
+					termLang = languageForCountry(cc)
+				}
+				clusters := clusterKeywords(dedupeClusterTerms(groups[cc]), termLang, threshold)
+				n := 0
+				for _, c := range clusters {
+					if len(c) < minSize {
+						continue
+					}
+					n++
+					out = append(out, clusterRow(cc, n, c))
+				}
+			}
+
+			return printOutput(out)
+		},
+	}
+
+	cmd.Flags().String("lang", "auto", "Stemming language: auto (from each row's country), en, de, fr, es, none")
+	cmd.Flags().Float64("threshold", 0.5, "Minimum similarity (0-1) between a term and a cluster head")
+	cmd.Flags().Int("min-size", 1, "Only report clusters with at least this many terms")
+	cmd.Flags().Bool("by-country", true, "Cluster each country separately")
+	return cmd
+}
+
+func readInputFile(path string) ([]byte, error) {
+	if strings.TrimSpace(path) == "" || path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// parseClusterTerms accepts any JSON value (or stream of values) produced by this CLI:
+// arrays of rows or single row objects.
+func parseClusterTerms(data []byte) ([]clusterTerm, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var out []clusterTerm
+	for {
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return out, nil
+			}
+			return nil, err
+		}
+		out = appendClusterTerms(out, v)
+	}
+}
+
+func appendClusterTerms(out []clusterTerm, v any) []clusterTerm {
+	switch t := v.(type) {
+	case []any:
+		for _, it := range t {
+			out = appendClusterTerms(out, it)
+		}
+	case map[string]any:
+		if rows, ok := t["rows"].([]any); ok {
+			// --envelope output: {"run": {...}, "rows": [...]}
+			return appendClusterTerms(out, rows)
+		}
+		term := stringField(t, "term")
+		if term == "" {
+			term = stringField(t, "keyword")
+		}
+		if term == "" {
+			return out
+		}
+		out = append(out, clusterTerm{
+			Term:       term,
+			Country:    strings.ToUpper(stringField(t, "country")),
+			Popularity: intField(t, "popularity"),
+		})
+	}
+	return out
+}
+
+func stringField(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return strings.TrimSpace(s)
+}
+
+func intField(m map[string]any, key string) *int {
+	switch v := m[key].(type) {
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			n := int(math.Round(f))
+			return &n
+		}
+	case float64:
+		n := int(math.Round(v))
+		return &n
+	case string:
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			return &n
+		}
+	}
+	return nil
+}
+
+// dedupeClusterTerms merges repeated terms, keeping the highest known popularity.
+func dedupeClusterTerms(terms []clusterTerm) []clusterTerm {
+	index := map[string]int{}
+	var out []clusterTerm
+	for _, t := range terms {
+		key := normKeyword(t.Term)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(out)
+			out = append(out, t)
+			continue
+		}
+		if t.Popularity != nil && (out[i].Popularity == nil || *t.Popularity > *out[i].Popularity) {
+			out[i].Popularity = t.Popularity
+		}
+	}
+	return out
+}
+
+func clusterKeywords(terms []clusterTerm, lang string, threshold float64) [][]clusterTerm {
+	for i := range terms {
+		terms[i].stems = keywordStems(terms[i].Term, lang)
+		terms[i].trigrams = charTrigrams(strings.Join(terms[i].stems, " "))
+	}
+
+	sort.SliceStable(terms, func(i, j int) bool {
+		pi, pj := popularityOrZero(terms[i].Popularity), popularityOrZero(terms[j].Popularity)
+		if pi != pj {
+			return pi > pj
+		}
+		if len(terms[i].Term) != len(terms[j].Term) {
+			return len(terms[i].Term) < len(terms[j].Term)
+		}
+		return normKeyword(terms[i].Term) < normKeyword(terms[j].Term)
+	})
+
+	var clusters [][]clusterTerm
+	for _, t := range terms {
+		joined := false
+		for ci := range clusters {
+			if keywordSimilarity(clusters[ci][0], t) >= threshold {
+				clusters[ci] = append(clusters[ci], t)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			clusters = append(clusters, []clusterTerm{t})
+		}
+	}
+	return clusters
+}
+
+// keywordSimilarity is the larger of the stem-set Jaccard index and the trigram Dice
+// coefficient, so both shared words and near-identical spellings pull terms together.
+func keywordSimilarity(a, b clusterTerm) float64 {
+	return math.Max(jaccard(a.stems, b.stems), dice(a.trigrams, b.trigrams))
+}
+
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	setA := map[string]bool{}
+	for _, s := range a {
+		setA[s] = true
+	}
+	setB := map[string]bool{}
+	for _, s := range b {
+		setB[s] = true
+	}
+	inter := 0
+	for s := range setA {
+		if setB[s] {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(setA)+len(setB)-inter)
+}
+
+func dice(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for g := range a {
+		if b[g] {
+			inter++
+		}
+	}
+	return 2 * float64(inter) / float64(len(a)+len(b))
+}
+
+func charTrigrams(s string) map[string]bool {
+	r := []rune(" " + s + " ")
+	out := map[string]bool{}
+	for i := 0; i+3 <= len(r); i++ {
+		out[string(r[i:i+3])] = true
+	}
+	return out
+}
+
+func popularityOrZero(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func clusterRow(country string, n int, members []clusterTerm) asoClusterRow {
+	row := asoClusterRow{
+		Country: country,
+		Cluster: n,
+		Head:    members[0].Term,
+		Size:    len(members),
+	}
+
+	known := 0
+	shared := map[string]int{}
+	for _, m := range members {
+		row.Terms = append(row.Terms, m.Term)
+		if m.Popularity != nil {
+			row.TotalPopularity += *m.Popularity
+			known++
+		}
+		seen := map[string]bool{}
+		for _, s := range m.stems {
+			if !seen[s] {
+				seen[s] = true
+				shared[s]++
+			}
+		}
+	}
+	if known > 0 {
+		row.AveragePopularity = math.Round(float64(row.TotalPopularity)/float64(known)*10) / 10
+	}
+	if len(members) > 1 {
+		for _, s := range members[0].stems {
+			if shared[s] == len(members) {
+				row.SharedStems = append(row.SharedStems, s)
+				shared[s] = 0
+			}
+		}
+	}
+	return row
+}
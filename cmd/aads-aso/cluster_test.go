@@ -0,0 +1,91 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParseClusterTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []string // term@country:popularity
+	}{
+		{"popscore rows", `[{"keyword":"photo","country":"us","popularity":41}]`, []string{"photo@US:41"}},
+		{"recommend rows", `[{"term":"photo editor","country":"GB","popularity":"7"}]`, []string{"photo editor@GB:7"}},
+		{"envelope", `{"run":{"id":"x"},"rows":[{"term":"a"},{"term":"b","popularity":3.6}]}`, []string{"a@:-", "b@:4"}},
+		{"ndjson stream", "{\"term\":\"a\"}\n{\"term\":\"b\"}\n", []string{"a@:-", "b@:-"}},
+		{"rows without term", `[{"country":"US"},{"term":"  "}]`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms, err := parseClusterTerms([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, term := range terms {
+				pop := "-"
+				if term.Popularity != nil {
+					pop = strconv.Itoa(*term.Popularity)
+				}
+				got = append(got, term.Term+"@"+term.Country+":"+pop)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseClusterTerms([]byte(`[{"term":`)); err == nil {
+		t.Error("truncated JSON: expected an error")
+	}
+}
+
+func TestClusterKeywords(t *testing.T) {
+	pop := func(n int) *int { return &n }
+	terms := dedupeClusterTerms([]clusterTerm{
+		{Term: "photo editor", Popularity: pop(30)},
+		{Term: "photo editing", Popularity: pop(20)},
+		{Term: "Photo Editor", Popularity: pop(45)},
+		{Term: "weather radar", Popularity: pop(50)},
+		{Term: "weather radars"},
+		{Term: "budget planner", Popularity: pop(10)},
+	})
+	clusters := clusterKeywords(terms, "en", 0.5)
+
+	var got [][]string
+	for _, c := range clusters {
+		var names []string
+		for _, term := range c {
+			names = append(names, term.Term)
+		}
+		got = append(got, names)
+	}
+	want := [][]string{
+		{"weather radar", "weather radars"},
+		{"photo editor", "photo editing"},
+		{"budget planner"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("clusters = %q, want %q", got, want)
+	}
+	if p := clusters[1][0].Popularity; p == nil || *p != 45 {
+		t.Errorf("deduped popularity = %v, want 45", p)
+	}
+}
+
+func TestSimilarityMeasures(t *testing.T) {
+	if got := jaccard([]string{"a", "b"}, []string{"b", "c"}); got != 1.0/3 {
+		t.Errorf("jaccard = %v, want 1/3", got)
+	}
+	if got := jaccard(nil, []string{"a"}); got != 0 {
+		t.Errorf("jaccard with empty set = %v, want 0", got)
+	}
+	// " abc " and " abd " share only " ab" of their three trigrams each.
+	a, b := charTrigrams("abc"), charTrigrams("abd")
+	if got := dice(a, b); got != 1.0/3 {
+		t.Errorf("dice(abc, abd) = %v, want 1/3", got)
+	}
+}
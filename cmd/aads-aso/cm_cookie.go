@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -19,9 +18,10 @@ import (
 func newASOCMCookieCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "cm-cookie",
-		Short: "Export app-ads.apple.com Cookie header via interactive Playwright login (local-only)",
+		Short: "Export app-ads.apple.com Cookie header via interactive browser login (local-only)",
 		Long: strings.TrimSpace(`
-Exports a Cookie header value for app-ads.apple.com by opening a real browser (Playwright) and waiting for you to log in.
+Exports a Cookie header value for app-ads.apple.com by opening a real browser (Playwright, or local Chrome
+with --browser-driver cdp) and waiting for you to log in.
 
 This is intended to refresh cookies used by:
   - aads aso popscore
@@ -30,6 +30,8 @@ This is intended to refresh cookies used by:
 Notes:
   - This is best-effort and may break if Apple changes login/session behavior.
   - You will still need to complete login/2FA in the browser; this command does not bypass it.
+  - Without a terminal (CI), or with --login-mode poll, it waits until the session cookies appear
+    (e.g. from a login saved in --profile-dir) instead of asking for Enter, and fails when 2FA is needed.
 `),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
@@ -52,6 +54,23 @@ Notes:
 
 			closeBrowser, _ := cmd.Flags().GetBool("close")
 			timeout, _ := cmd.Flags().GetDuration("timeout")
+			driver, browserPath, err := getBrowserDriverFlags(cmd)
+			if err != nil {
+				return err
+			}
+			loginMode, loginTimeout, loginCookies, err := getLoginWaitFlags(cmd)
+			if err != nil {
+				return err
+			}
+			warmUp, warmUpURL := getWarmUpFlags(cmd)
+			extraHeaders, err := getExtraHeaders(cmd)
+			if err != nil {
+				return err
+			}
+			if loginMode == loginModePoll && !cmd.Flags().Changed("headed") {
+				// Nobody is watching; CI machines usually have no display either.
+				headed = false
+			}
 
 			cookieHeader, err := refreshCMCookieInteractively(ctx, cmCookieRefreshOptions{
 				URL:          url,
@@ -60,15 +79,22 @@ Notes:
 				Headed:       headed,
 				CloseBrowser: closeBrowser,
 				Timeout:      timeout,
-				Prompt:       true,
+				LoginMode:    loginMode,
+				LoginTimeout: loginTimeout,
+				LoginCookies: loginCookies,
+				WarmUp:       warmUp,
+				WarmUpURL:    warmUpURL,
+				ExtraHeaders: extraHeaders,
+				Driver:       driver,
+				BrowserPath:  browserPath,
 			})
 			if err != nil {
 				return err
 			}
 
 			if outPath != "" {
-				fmt.Fprintf(os.Stderr, "Wrote cookie to %s\n", outPath)
-				fmt.Fprintf(os.Stderr, "Use it with: aads-aso popscore --cookie-file %s ...\n", outPath)
+				logInfo("cookie_saved", "Wrote cookie to "+outPath, "path", outPath)
+				logInfo("usage_hint", fmt.Sprintf("Use it with: aads-aso popscore --cookie-file %s ...", outPath))
 				return nil
 			}
 			fmt.Fprintln(os.Stdout, cookieHeader)
@@ -78,10 +104,14 @@ Notes:
 
 	cmd.Flags().String("url", "https://app-ads.apple.com/", "URL to open (Apple Ads web)")
 	cmd.Flags().Bool("headed", true, "Open the browser in headed mode")
-	cmd.Flags().String("profile-dir", "", "Playwright persistent profile directory (defaults to ~/.aads/playwright-app-ads-profile)")
-	cmd.Flags().String("out", "", "Write cookie header value to this file (0600). If empty, prints to stdout.")
+	cmd.Flags().String("profile-dir", "", "Persistent browser profile directory (defaults to ~/.aads/playwright-app-ads-profile)")
+	cmd.Flags().String("out", "", "Write cookie header value to this file (0600, encrypted when a cookie key is set). If empty, prints to stdout.")
 	cmd.Flags().Bool("close", true, "Close the browser after exporting cookies")
 	cmd.Flags().Duration("timeout", 2*time.Minute, "Max time for cookie extraction after you press Enter")
+	addBrowserDriverFlags(cmd)
+	addLoginWaitFlags(cmd)
+	addWarmUpFlags(cmd)
+	addExtraHeaderFlags(cmd)
 
 	return cmd
 }
@@ -109,7 +139,14 @@ type cmCookieRefreshOptions struct {
 	Headed       bool
 	CloseBrowser bool
 	Timeout      time.Duration
-	Prompt       bool
+	LoginMode    string            // loginModePrompt (default) or loginModePoll
+	LoginTimeout time.Duration     // deadline for loginModePoll
+	LoginCookies []string          // cookies loginModePoll waits for
+	WarmUp       bool              // open an ad group's Add Keywords page before exporting
+	WarmUpURL    string            // page template for WarmUp ({campaignId}, {adGroupId}, {adamId})
+	ExtraHeaders map[string]string // --header values for WarmUp's Apple Ads API calls
+	Driver       string            // browserDriverPlaywright (default) or browserDriverCDP
+	BrowserPath  string            // Chrome/Chromium executable for the CDP driver
 }
 
 func refreshCMCookieInteractively(ctx context.Context, opts cmCookieRefreshOptions) (string, error) {
@@ -127,6 +164,15 @@ func refreshCMCookieInteractively(ctx context.Context, opts cmCookieRefreshOptio
 		return "", fmt.Errorf("create profile dir: %w", err)
 	}
 
+	opts.ProfileDir = profileDir
+
+	if opts.Driver == browserDriverCDP {
+		return refreshCMCookieViaCDP(ctx, opts, url, profileDir)
+	}
+	return refreshCMCookieViaPlaywright(ctx, opts, url, profileDir)
+}
+
+func refreshCMCookieViaPlaywright(ctx context.Context, opts cmCookieRefreshOptions, url, profileDir string) (string, error) {
 	// Unique session name so this doesn't clash with other Playwright CLI usage.
 	session := newCMCookieSessionName()
 
@@ -140,7 +186,7 @@ func refreshCMCookieInteractively(ctx context.Context, opts cmCookieRefreshOptio
 		// Fallback when persistent Chrome profile is already in use by another process.
 		// We can still refresh cookie in a non-persistent browser context and save it to file.
 		if isPersistentBrowserInUseErr(err) {
-			fmt.Fprintln(os.Stderr, "Persistent browser profile is busy; retrying with a temporary browser context...")
+			logWarn("retry", "Persistent browser profile is busy; retrying with a temporary browser context...", "reason", "profile_in_use")
 			openArgs = []string{"--session", session, "open", url}
 			if opts.Headed {
 				openArgs = append(openArgs, "--headed")
@@ -153,57 +199,113 @@ func refreshCMCookieInteractively(ctx context.Context, opts cmCookieRefreshOptio
 		}
 	}
 
-	// 2) Wait for user to complete login.
-	if opts.Prompt {
-		fmt.Fprintln(os.Stderr, "Browser opened. Complete Apple Ads login in the browser window.")
-		fmt.Fprintln(os.Stderr, "When you are logged in, press Enter here to export cookies...")
-		_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	// Close the browser once done, also when login is not recognized in poll mode.
+	if opts.CloseBrowser {
+		defer func() {
+			_, _ = runPlaywrightCLI(context.WithoutCancel(ctx), "--session", session, "close")
+		}()
 	}
 
-	// 3) Extract cookie header string from the browser context.
-	extractFn := "async (page) => {\n" +
-		"  const cookies = await page.context().cookies('https://app-ads.apple.com');\n" +
-		"  return cookies.map(c => `${c.name}=${c.value}`).join('; ');\n" +
-		"}"
-
-	extractCtx := ctx
-	if opts.Timeout > 0 {
-		var cancel context.CancelFunc
-		extractCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
-		defer cancel()
+	// 2) Wait for login, then 3) extract cookies (with their expiry) from the browser context.
+	probe := func(ctx context.Context) (cmLoginState, error) {
+		return playwrightLoginProbe(ctx, session)
 	}
-
-	out, err := runPlaywrightCLI(extractCtx, "--session", session, "run-code", extractFn)
-	if err != nil {
-		return "", err
+	var cookies []exportedCookie
+	if opts.LoginMode == loginModePoll {
+		var err error
+		if cookies, err = waitForCMLogin(ctx, opts, probe); err != nil {
+			return "", err
+		}
+	} else {
+		promptForLogin(url)
+		extractCtx, cancel := withOptionalTimeout(ctx, opts.Timeout)
+		defer cancel()
+		state, err := probe(extractCtx)
+		if err != nil {
+			return "", err
+		}
+		cookies = state.Cookies
 	}
-
-	cookieHeader, err := parsePWCLIResultString(out)
-	if err != nil {
-		return "", err
+	navigate := func(ctx context.Context, url string) error {
+		return playwrightNavigate(ctx, session, url)
 	}
-	cookieHeader = strings.TrimSpace(cookieHeader)
+	cookies = warmUpCMSession(ctx, opts, cookies, navigate, probe)
+	cookieHeader := buildCMCookieHeader(cookies, time.Now())
 	if cookieHeader == "" {
 		return "", fmt.Errorf("exported cookie is empty; are you logged in to app-ads.apple.com in the opened browser?")
 	}
 
-	// 4) Optionally close the browser.
-	if opts.CloseBrowser {
-		_, _ = runPlaywrightCLI(ctx, "--session", session, "close")
-	}
-
 	if strings.TrimSpace(opts.OutPath) != "" {
-		if err := os.MkdirAll(filepath.Dir(opts.OutPath), 0o700); err != nil {
-			return "", fmt.Errorf("create cookie file dir: %w", err)
-		}
-		if err := os.WriteFile(opts.OutPath, []byte(cookieHeader+"\n"), 0o600); err != nil {
-			return "", fmt.Errorf("write cookie file: %w", err)
+		if err := saveCookieFile(opts.OutPath, cookieHeader, "playwright", cookies); err != nil {
+			return "", err
 		}
 	}
 
 	return cookieHeader, nil
 }
 
+// cmLoginProbeJS reports the page URL, whether an Apple sign-in frame is showing
+// (and asks for a verification code), and the cookies for app-ads.apple.com.
+const cmLoginProbeJS = `async (page) => {
+  const cookies = await page.context().cookies('https://app-ads.apple.com');
+  let signIn = false, twoFactor = false;
+  for (const f of page.frames()) {
+    if (!/(idmsa|appleid)\.apple\.com/.test(f.url())) continue;
+    signIn = true;
+    try {
+      const text = await f.evaluate(() => document.body ? document.body.innerText : '');
+      if (/verification code|two-factor|trusted device/i.test(text)) twoFactor = true;
+    } catch (e) {}
+  }
+  return JSON.stringify({url: page.url(), signIn, twoFactor,
+    cookies: cookies.map(c => ({name: c.name, value: c.value, domain: c.domain, path: c.path, expires: c.expires}))});
+}`
+
+func playwrightLoginProbe(ctx context.Context, session string) (cmLoginState, error) {
+	out, err := runPlaywrightCLI(ctx, "--session", session, "run-code", cmLoginProbeJS)
+	if err != nil {
+		return cmLoginState{}, err
+	}
+	resultJSON, err := parsePWCLIResultString(out)
+	if err != nil {
+		return cmLoginState{}, err
+	}
+	var result struct {
+		URL       string `json:"url"`
+		SignIn    bool   `json:"signIn"`
+		TwoFactor bool   `json:"twoFactor"`
+		Cookies   []any  `json:"cookies"`
+	}
+	if err := json.Unmarshal([]byte(resultJSON), &result); err != nil {
+		return cmLoginState{}, fmt.Errorf("parse exported cookies: %w", err)
+	}
+	return cmLoginState{
+		URL:       result.URL,
+		SignIn:    result.SignIn,
+		TwoFactor: result.TwoFactor,
+		Cookies:   parseJSONCookies(result.Cookies),
+	}, nil
+}
+
+// playwrightNavigate opens url in the session's page and waits for it to settle.
+func playwrightNavigate(ctx context.Context, session, url string) error {
+	target, err := json.Marshal(url)
+	if err != nil {
+		return err
+	}
+	navigateFn := fmt.Sprintf("async (page) => {\n"+
+		"  await page.goto(%s, {waitUntil: 'load', timeout: 30000});\n"+
+		"  await page.waitForTimeout(%d);\n"+
+		"  return page.url();\n"+
+		"}", target, warmUpSettle.Milliseconds())
+	out, err := runPlaywrightCLI(ctx, "--session", session, "run-code", navigateFn)
+	if err != nil {
+		return err
+	}
+	_, err = parsePWCLIResultString(out)
+	return err
+}
+
 func newCMCookieSessionName() string {
 	var suffix [4]byte
 	if _, err := rand.Read(suffix[:]); err == nil {
@@ -104,87 +104,27 @@ func newASOPopscoreCmd() *cobra.Command {
 				return fmt.Errorf("no keywords provided (use --keywords or --keywords-file)")
 			}
 
-			cookie, err := getCookieFlag(ctx, cmd)
+			session, err := newCMSessionFromFlags(ctx, cmd, countries)
 			if err != nil {
 				return err
 			}
 
-			extraHeaders, err := getExtraHeaders(cmd)
-			if err != nil {
-				return err
-			}
-			autoCookie, _ := cmd.Flags().GetBool("auto-cookie")
-			timeout, _ := cmd.Flags().GetDuration("timeout")
-			adamID, cookie, err := resolveAdamIDForCMCommand(ctx, cmd, countries, cookie, extraHeaders, autoCookie, timeout)
-			if err != nil {
-				return err
-			}
-
-			callPopularities := func(cookieValue, country string) ([]cmKeywordItem, error) {
-				reqCtx, cancel := withOptionalTimeout(ctx, timeout)
-				defer cancel()
-				return cmKeywordPopularities(reqCtx, cookieValue, extraHeaders, adamID, country, keywords)
-			}
-
 			var out []asoPopscoreRow
-			attemptedOwnedAdamFallback := false
 			for _, cc := range countries {
-				respItems, err := callPopularities(cookie, cc)
-				if err != nil && autoCookie && isCMRefreshError(err) {
-					fmt.Fprintln(os.Stderr, "Cookie appears expired. Launching browser to refresh session...")
-					cookie, err = refreshCMCookieFromFlags(ctx, cmd)
-					if err != nil {
-						return err
-					}
-					respItems, err = callPopularities(cookie, cc)
-				}
-				if err != nil && !attemptedOwnedAdamFallback && isCMNoUserOwnedAppsError(err) {
-					attemptedOwnedAdamFallback = true
-					ownedAdamID, updatedCookie, discoverErr := discoverOwnedAdamIDWithRefresh(ctx, cmd, cookie, extraHeaders, autoCookie, timeout)
-					if discoverErr != nil {
-						return fmt.Errorf("adam-id %d is not accessible for this Apple Ads account, and auto-discovery failed: %w", adamID, discoverErr)
-					}
-					if ownedAdamID > 0 && ownedAdamID != adamID {
-						fmt.Fprintf(os.Stderr, "adam-id %d is not owned by this account; switching to owned adam-id %d and retrying...\n", adamID, ownedAdamID)
-						adamID = ownedAdamID
-					}
-					cookie = updatedCookie
-					respItems, err = callPopularities(cookie, cc)
-				}
+				done := trackRunCountry(cc)
+				respItems, err := session.popularities(ctx, cc, keywords)
+				done(err)
 				if err != nil {
 					return err
 				}
-
-				byName := map[string]cmKeywordItem{}
-				for _, it := range respItems {
-					byName[normKeyword(it.Name)] = it
-				}
-
-				for _, kw := range keywords {
-					it, ok := byName[normKeyword(kw)]
-					row := asoPopscoreRow{
-						Keyword: kw,
-						Country: cc,
-						Found:   ok,
-						Source:  "cm_api_v2",
-					}
-					if ok {
-						pop := it.Popularity
-						mt := strings.TrimSpace(it.MatchType)
-						row.Popularity = &pop
-						if mt != "" {
-							row.MatchType = &mt
-						}
-					}
-					out = append(out, row)
-				}
+				out = append(out, popscoreRows(keywords, cc, respItems)...)
 			}
 
 			return printOutput(out)
 		},
 	}
 
-	addCommonCMKeywordFlags(cmd)
+	addCommonCMKeywordFlags(cmd, true)
 	cmd.Flags().String("keywords", "", "Comma-separated keywords")
 	cmd.Flags().String("keywords-file", "", "Path to file with one keyword per line")
 	return cmd
@@ -209,19 +149,9 @@ func newASORecommendCmd() *cobra.Command {
 			if seed == "" {
 				return fmt.Errorf("--text is required")
 			}
+			recordRunSeed(seed)
 
-			cookie, err := getCookieFlag(ctx, cmd)
-			if err != nil {
-				return err
-			}
-
-			extraHeaders, err := getExtraHeaders(cmd)
-			if err != nil {
-				return err
-			}
-			autoCookie, _ := cmd.Flags().GetBool("auto-cookie")
-			timeout, _ := cmd.Flags().GetDuration("timeout")
-			adamID, cookie, err := resolveAdamIDForCMCommand(ctx, cmd, countries, cookie, extraHeaders, autoCookie, timeout)
+			session, err := newCMSessionFromFlags(ctx, cmd, countries)
 			if err != nil {
 				return err
 			}
@@ -232,37 +162,11 @@ func newASORecommendCmd() *cobra.Command {
 			}
 			minPop, _ := cmd.Flags().GetInt("min-popularity")
 
-			callRecommendations := func(cookieValue, country string) ([]cmKeywordItem, error) {
-				reqCtx, cancel := withOptionalTimeout(ctx, timeout)
-				defer cancel()
-				return cmKeywordRecommendation(reqCtx, cookieValue, extraHeaders, adamID, country, seed)
-			}
-
 			var out []asoRecommendRow
-			attemptedOwnedAdamFallback := false
 			for _, cc := range countries {
-				items, err := callRecommendations(cookie, cc)
-				if err != nil && autoCookie && isCMRefreshError(err) {
-					fmt.Fprintln(os.Stderr, "Cookie appears expired. Launching browser to refresh session...")
-					cookie, err = refreshCMCookieFromFlags(ctx, cmd)
-					if err != nil {
-						return err
-					}
-					items, err = callRecommendations(cookie, cc)
-				}
-				if err != nil && !attemptedOwnedAdamFallback && isCMNoUserOwnedAppsError(err) {
-					attemptedOwnedAdamFallback = true
-					ownedAdamID, updatedCookie, discoverErr := discoverOwnedAdamIDWithRefresh(ctx, cmd, cookie, extraHeaders, autoCookie, timeout)
-					if discoverErr != nil {
-						return fmt.Errorf("adam-id %d is not accessible for this Apple Ads account, and auto-discovery failed: %w", adamID, discoverErr)
-					}
-					if ownedAdamID > 0 && ownedAdamID != adamID {
-						fmt.Fprintf(os.Stderr, "adam-id %d is not owned by this account; switching to owned adam-id %d and retrying...\n", adamID, ownedAdamID)
-						adamID = ownedAdamID
-					}
-					cookie = updatedCookie
-					items, err = callRecommendations(cookie, cc)
-				}
+				done := trackRunCountry(cc)
+				items, err := session.recommendation(ctx, cc, seed)
+				done(err)
 				if err != nil {
 					return err
 				}
@@ -288,18 +192,13 @@ func newASORecommendCmd() *cobra.Command {
 				}
 
 				for i, it := range kept {
-					pop := it.Popularity
-					mt := strings.TrimSpace(it.MatchType)
-					var mtPtr *string
-					if mt != "" {
-						mtPtr = &mt
-					}
+					pop, mt := cmItemPopularity(it)
 					out = append(out, asoRecommendRow{
 						Country:    cc,
 						Seed:       seed,
 						Term:       it.Name,
-						Popularity: &pop,
-						MatchType:  mtPtr,
+						Popularity: pop,
+						MatchType:  mt,
 						Rank:       i + 1,
 						Source:     "cm_api_v2",
 					})
@@ -310,7 +209,7 @@ func newASORecommendCmd() *cobra.Command {
 		},
 	}
 
-	addCommonCMKeywordFlags(cmd)
+	addCommonCMKeywordFlags(cmd, true)
 	cmd.Flags().String("text", "", "Seed text to get related keyword recommendations")
 	_ = cmd.MarkFlagRequired("text")
 	cmd.Flags().Int("limit", 50, "Max recommendations per country")
@@ -318,59 +217,108 @@ func newASORecommendCmd() *cobra.Command {
 	return cmd
 }
 
-func addCommonCMKeywordFlags(cmd *cobra.Command) {
+func popscoreRows(keywords []string, country string, items []cmKeywordItem) []asoPopscoreRow {
+	byName := map[string]cmKeywordItem{}
+	for _, it := range items {
+		byName[normKeyword(it.Name)] = it
+	}
+
+	out := make([]asoPopscoreRow, 0, len(keywords))
+	for _, kw := range keywords {
+		it, ok := byName[normKeyword(kw)]
+		row := asoPopscoreRow{
+			Keyword: kw,
+			Country: country,
+			Found:   ok,
+			Source:  "cm_api_v2",
+		}
+		if ok {
+			row.Popularity, row.MatchType = cmItemPopularity(it)
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+func cmItemPopularity(it cmKeywordItem) (*int, *string) {
+	pop := it.Popularity
+	mt := strings.TrimSpace(it.MatchType)
+	if mt == "" {
+		return &pop, nil
+	}
+	return &pop, &mt
+}
+
+// addCommonCMKeywordFlags registers the flags of the keyword commands; autoCookie is
+// the default of --auto-cookie.
+func addCommonCMKeywordFlags(cmd *cobra.Command, autoCookie bool) {
 	cmd.Flags().String("countries", "", "Comma-separated country codes (alpha-2), e.g. US,GB")
 	_ = cmd.MarkFlagRequired("countries")
 	cmd.Flags().Int64("adam-id", 0, "App Store app adamId (optional when auto-resolving from other app flags)")
 	cmd.Flags().String("app-url", "", "App Store URL (extracts adamId automatically)")
 	cmd.Flags().String("bundle-id", "", "Bundle ID to auto-resolve adamId via iTunes Lookup")
 	cmd.Flags().String("app-name", "", "App name to auto-resolve adamId via iTunes Search")
+	addMinConfidenceFlag(cmd)
 	cmd.Flags().String("adam-country", "", "Country for adamId lookup/search (defaults to first --countries value)")
-	addCookieFlags(cmd)
+	cmd.Flags().String("owned-app", "", "When no adam-id is given, use this of the account's apps (adam-id, bundle ID or name); required non-interactively if the account has several")
+	addCookieFlags(cmd, autoCookie)
 	addExtraHeaderFlags(cmd)
 	cmd.Flags().Duration("timeout", 30*time.Second, "Request timeout per country")
 }
 
-func addCookieFlags(cmd *cobra.Command) {
+// addCookieFlags registers the cookie and browser refresh flags. Commands that still
+// work without a session pass autoCookie false, so a browser only opens on request.
+func addCookieFlags(cmd *cobra.Command, autoCookie bool) {
+	autoCookieUsage := "If cookie is missing/expired, open a browser (--browser-driver) for interactive refresh"
+	if !autoCookie {
+		autoCookieUsage = "If cookie is missing/expired, open a browser (--browser-driver) for interactive refresh; off by default, so results without a session come back without popularity"
+	}
 	cmd.Flags().String("cookie", "", "Cookie header value (e.g. 'a=b; c=d') from an authenticated app-ads.apple.com session")
 	cmd.Flags().String("cookie-file", defaultCMCookieFilePath(), "Path to file containing Cookie header value (also used as cache when --auto-cookie is enabled)")
-	cmd.Flags().Bool("auto-cookie", true, "If cookie is missing/expired, open Playwright for interactive refresh")
-	cmd.Flags().String("cookie-profile-dir", "", "Playwright persistent profile directory for cookie refresh")
+	cmd.Flags().Bool("auto-cookie", autoCookie, autoCookieUsage)
+	cmd.Flags().String("cookie-profile-dir", "", "Persistent browser profile directory for cookie refresh")
+	addCookieExpiryFlags(cmd)
+	addCookieProviderFlags(cmd)
+	addBrowserDriverFlags(cmd)
+	addLoginWaitFlags(cmd)
+	addWarmUpFlags(cmd)
 }
 
 func addExtraHeaderFlags(cmd *cobra.Command) {
 	cmd.Flags().StringArray("header", nil, "Extra request header 'Name: value' (repeatable)")
 }
 
-func getCookieFlag(ctx context.Context, cmd *cobra.Command) (string, error) {
-	cookie, _ := cmd.Flags().GetString("cookie")
-	cookieFile, _ := cmd.Flags().GetString("cookie-file")
-	autoCookie, _ := cmd.Flags().GetBool("auto-cookie")
-
-	cookie = strings.TrimSpace(cookie)
-	if cookie == "" && strings.TrimSpace(cookieFile) != "" {
-		b, err := os.ReadFile(cookieFile)
-		if err != nil {
-			if !errors.Is(err, os.ErrNotExist) {
-				return "", err
-			}
-		} else {
-			cookie = strings.TrimSpace(string(b))
+// readCookieFile returns the Cookie header stored at path, or "" when the file does
+// not exist. The file may be encrypted, and may be a cookies.txt, HAR or JSON export
+// instead of a plain header.
+func readCookieFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
 		}
+		return "", err
+	}
+	cookie, err := decodeCookieFile(path, string(b))
+	if err != nil {
+		return "", err
 	}
+	header, _, isExport, err := cookieHeaderFromExport(cookie, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("cookie file %s: %w", path, err)
+	}
+	if isExport {
+		return header, nil
+	}
+	return trimCookieHeader(cookie), nil
+}
 
+func trimCookieHeader(cookie string) string {
+	cookie = strings.TrimSpace(cookie)
 	if strings.HasPrefix(strings.ToLower(cookie), "cookie:") {
 		cookie = strings.TrimSpace(cookie[len("cookie:"):])
 	}
-
-	if cookie == "" {
-		if !autoCookie {
-			return "", fmt.Errorf("--cookie (or --cookie-file) is required for this command")
-		}
-		fmt.Fprintln(os.Stderr, "Cookie not found. Launching browser to refresh session...")
-		return refreshCMCookieFromFlags(ctx, cmd)
-	}
-	return cookie, nil
+	return cookie
 }
 
 func refreshCMCookieFromFlags(ctx context.Context, cmd *cobra.Command) (string, error) {
@@ -379,15 +327,35 @@ func refreshCMCookieFromFlags(ctx context.Context, cmd *cobra.Command) (string,
 	if strings.TrimSpace(cookieFile) == "" {
 		cookieFile = defaultCMCookieFilePath()
 	}
+	driver, browserPath, err := getBrowserDriverFlags(cmd)
+	if err != nil {
+		return "", err
+	}
+	loginMode, loginTimeout, loginCookies, err := getLoginWaitFlags(cmd)
+	if err != nil {
+		return "", err
+	}
+	warmUp, warmUpURL := getWarmUpFlags(cmd)
+	extraHeaders, err := getExtraHeaders(cmd)
+	if err != nil {
+		return "", err
+	}
 
 	return refreshCMCookieInteractively(ctx, cmCookieRefreshOptions{
 		URL:          "https://app-ads.apple.com/",
 		ProfileDir:   profileDir,
 		OutPath:      cookieFile,
-		Headed:       true,
+		Headed:       loginMode != loginModePoll,
 		CloseBrowser: true,
 		Timeout:      2 * time.Minute,
-		Prompt:       true,
+		LoginMode:    loginMode,
+		LoginTimeout: loginTimeout,
+		LoginCookies: loginCookies,
+		WarmUp:       warmUp,
+		WarmUpURL:    warmUpURL,
+		ExtraHeaders: extraHeaders,
+		Driver:       driver,
+		BrowserPath:  browserPath,
 	})
 }
 
@@ -416,13 +384,105 @@ func getExtraHeaders(cmd *cobra.Command) (map[string]string, error) {
 	return out, nil
 }
 
+// cmSession holds the cookie and adam-id shared by the Apple Ads keyword calls of one
+// command run. Calls made through it refresh an expired cookie through the cookie
+// providers and fall back once to an owned adam-id when the current one is not accessible.
+type cmSession struct {
+	cmd          *cobra.Command
+	cookie       string
+	cookies      *cookieChain
+	extraHeaders map[string]string
+	adamID       int64
+	ownedApp     ownedAppSelector
+	timeout      time.Duration
+
+	attemptedOwnedAdamFallback bool
+}
+
+func newCMSessionFromFlags(ctx context.Context, cmd *cobra.Command, countries []string) (*cmSession, error) {
+	cookies, err := newCookieChainFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cookie, err := cookies.Cookie(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	extraHeaders, err := getExtraHeaders(cmd)
+	if err != nil {
+		return nil, err
+	}
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	adamID, cookie, err := resolveAdamIDForCMCommand(ctx, cmd, countries, cookie, cookies, extraHeaders, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cmSession{
+		cmd:          cmd,
+		cookie:       cookie,
+		cookies:      cookies,
+		extraHeaders: extraHeaders,
+		adamID:       adamID,
+		ownedApp:     ownedAppSelectorFromFlags(cmd, countries),
+		timeout:      timeout,
+	}, nil
+}
+
+func (s *cmSession) popularities(ctx context.Context, country string, terms []string) ([]cmKeywordItem, error) {
+	return s.call(ctx, func(reqCtx context.Context) ([]cmKeywordItem, error) {
+		return cmKeywordPopularities(reqCtx, s.cookie, s.extraHeaders, s.adamID, country, terms)
+	})
+}
+
+func (s *cmSession) recommendation(ctx context.Context, country, text string) ([]cmKeywordItem, error) {
+	return s.call(ctx, func(reqCtx context.Context) ([]cmKeywordItem, error) {
+		return cmKeywordRecommendation(reqCtx, s.cookie, s.extraHeaders, s.adamID, country, text)
+	})
+}
+
+func (s *cmSession) call(ctx context.Context, fn func(reqCtx context.Context) ([]cmKeywordItem, error)) ([]cmKeywordItem, error) {
+	callOnce := func() ([]cmKeywordItem, error) {
+		reqCtx, cancel := withOptionalTimeout(ctx, s.timeout)
+		defer cancel()
+		return fn(reqCtx)
+	}
+
+	items, err := callOnce()
+	if err != nil && s.cookies.canRefresh() && isCMRefreshError(err) {
+		s.cookie, err = s.cookies.Refresh(ctx)
+		if err != nil {
+			return nil, err
+		}
+		logDebug("retry", "Retrying with refreshed cookie", "reason", "cookie_refreshed")
+		items, err = callOnce()
+	}
+	if err != nil && !s.attemptedOwnedAdamFallback && isCMNoUserOwnedAppsError(err) {
+		s.attemptedOwnedAdamFallback = true
+		owned, updatedCookie, discoverErr := discoverOwnedAdamIDWithRefresh(ctx, s.cookie, s.cookies, s.extraHeaders, s.timeout, s.ownedApp)
+		if discoverErr != nil {
+			return nil, fmt.Errorf("adam-id %d is not accessible for this Apple Ads account, and auto-discovery failed: %w", s.adamID, discoverErr)
+		}
+		if owned.AdamID > 0 && owned.AdamID != s.adamID {
+			warnRun("retry", fmt.Sprintf("adam-id %d is not owned by this account; switching to owned adam-id %d and retrying...", s.adamID, owned.AdamID),
+				"reason", "adam_id_not_owned", "adam_id", s.adamID, "owned_adam_id", owned.AdamID)
+			s.adamID = owned.AdamID
+			recordRunAdamID(owned.AdamID, adamIDSourceOwnedFallback, owned.Name)
+		}
+		s.cookie = updatedCookie
+		items, err = callOnce()
+	}
+	return items, err
+}
+
 func resolveAdamIDForCMCommand(
 	ctx context.Context,
 	cmd *cobra.Command,
 	countries []string,
 	cookie string,
+	cookies *cookieChain,
 	extraHeaders map[string]string,
-	autoCookie bool,
 	timeout time.Duration,
 ) (int64, string, error) {
 	adamID, err := resolveAdamIDFromFlags(ctx, cmd, countries)
@@ -433,48 +493,46 @@ func resolveAdamIDForCMCommand(
 		return 0, cookie, err
 	}
 
-	ownedAdamID, updatedCookie, discoverErr := discoverOwnedAdamIDWithRefresh(ctx, cmd, cookie, extraHeaders, autoCookie, timeout)
+	owned, updatedCookie, discoverErr := discoverOwnedAdamIDWithRefresh(ctx, cookie, cookies, extraHeaders, timeout, ownedAppSelectorFromFlags(cmd, countries))
 	if discoverErr != nil {
 		return 0, cookie, fmt.Errorf("auto-resolve adam-id from Apple Ads account: %w", discoverErr)
 	}
-	fmt.Fprintf(os.Stderr, "Resolved adam-id=%d from Apple Ads owned campaigns\n", ownedAdamID)
-	return ownedAdamID, updatedCookie, nil
+	logInfo("adam_resolved", fmt.Sprintf("Resolved adam-id=%d from Apple Ads owned campaigns", owned.AdamID), "adam_id", owned.AdamID, "source", adamIDSourceOwnedCampaign)
+	recordRunAdamID(owned.AdamID, adamIDSourceOwnedCampaign, owned.Name)
+	return owned.AdamID, updatedCookie, nil
 }
 
 func discoverOwnedAdamIDWithRefresh(
 	ctx context.Context,
-	cmd *cobra.Command,
 	cookie string,
+	cookies *cookieChain,
 	extraHeaders map[string]string,
-	autoCookie bool,
 	timeout time.Duration,
-) (int64, string, error) {
-	discover := func(cookieValue string) (int64, error) {
+	sel ownedAppSelector,
+) (ownedAppCandidate, string, error) {
+	discover := func(cookieValue string) (ownedAppCandidate, error) {
 		reqCtx, cancel := withOptionalTimeout(ctx, timeout)
 		defer cancel()
-		adamID, campaignName, err := cmDiscoverOwnedAdamID(reqCtx, cookieValue, extraHeaders)
+		app, err := cmDiscoverOwnedAdamID(reqCtx, cookieValue, extraHeaders, sel)
 		if err != nil {
-			return 0, err
-		}
-		if strings.TrimSpace(campaignName) != "" {
-			fmt.Fprintf(os.Stderr, "Selected owned adam-id=%d from campaign %q\n", adamID, campaignName)
+			return app, err
 		}
-		return adamID, nil
+		logInfo("owned_app_selected", "Selected owned app "+app.String(), "adam_id", app.AdamID, "app_name", app.Name, "bundle_id", app.BundleID, "campaigns", app.Campaigns)
+		return app, nil
 	}
 
-	adamID, err := discover(cookie)
-	if err != nil && autoCookie && isCMRefreshError(err) {
-		fmt.Fprintln(os.Stderr, "Cookie appears expired while discovering owned apps. Launching browser to refresh session...")
-		cookie, err = refreshCMCookieFromFlags(ctx, cmd)
+	app, err := discover(cookie)
+	if err != nil && cookies.canRefresh() && isCMRefreshError(err) {
+		cookie, err = cookies.Refresh(ctx)
 		if err != nil {
-			return 0, cookie, err
+			return app, cookie, err
 		}
-		adamID, err = discover(cookie)
+		app, err = discover(cookie)
 	}
 	if err != nil {
-		return 0, cookie, err
+		return app, cookie, err
 	}
-	return adamID, cookie, nil
+	return app, cookie, nil
 }
 
 func cmKeywordPopularities(
@@ -531,20 +589,13 @@ func cmDiscoverOwnedAdamID(
 	ctx context.Context,
 	cookie string,
 	extraHeaders map[string]string,
-) (int64, string, error) {
+	sel ownedAppSelector,
+) (ownedAppCandidate, error) {
 	campaigns, err := cmCampaignsFind(ctx, cookie, extraHeaders)
 	if err != nil {
-		return 0, "", err
-	}
-	seen := map[int64]bool{}
-	for _, it := range campaigns {
-		if it.AdamID <= 0 || seen[it.AdamID] {
-			continue
-		}
-		seen[it.AdamID] = true
-		return it.AdamID, strings.TrimSpace(it.Name), nil
+		return ownedAppCandidate{}, err
 	}
-	return 0, "", fmt.Errorf("no owned adam-id found in Apple Ads campaigns")
+	return selectOwnedApp(ctx, ownedAppCandidates(campaigns), sel)
 }
 
 func cmCampaignsFind(
@@ -564,19 +615,7 @@ func parseCMCampaignData(endpoint string, body []byte) ([]cmCampaignItem, error)
 	if err := json.Unmarshal(body, &ok); err == nil && (ok.Status == "" || strings.EqualFold(ok.Status, "success")) {
 		return ok.Data, nil
 	}
-
-	var er cmErrorResponse
-	if err := json.Unmarshal(body, &er); err == nil && (er.ErrorMsg != "" || er.ErrorCode != "" || er.InternalErrorCode != "") {
-		return nil, fmt.Errorf("cm %s error (%s/%s): %s", endpoint, strings.TrimSpace(er.ErrorCode), strings.TrimSpace(er.InternalErrorCode), strings.TrimSpace(er.ErrorMsg))
-	}
-
-	var n cmErrorNestedResponse
-	if err := json.Unmarshal(body, &n); err == nil && len(n.Error.Errors) > 0 {
-		first := n.Error.Errors[0]
-		return nil, fmt.Errorf("cm %s error (%s): %s", endpoint, strings.TrimSpace(first.MessageCode), strings.TrimSpace(first.Message))
-	}
-
-	return nil, fmt.Errorf("cm %s: unexpected response: %s", endpoint, strings.TrimSpace(string(body)))
+	return nil, cmResponseError(endpoint, body)
 }
 
 func parseCMKeywordData(endpoint string, body []byte) ([]cmKeywordItem, error) {
@@ -584,19 +623,24 @@ func parseCMKeywordData(endpoint string, body []byte) ([]cmKeywordItem, error) {
 	if err := json.Unmarshal(body, &ok); err == nil && (ok.Status == "" || strings.EqualFold(ok.Status, "success")) {
 		return ok.Data, nil
 	}
+	return nil, cmResponseError(endpoint, body)
+}
 
+// cmResponseError describes a response body that is not a success, in either of the
+// error shapes the API uses.
+func cmResponseError(endpoint string, body []byte) error {
 	var er cmErrorResponse
 	if err := json.Unmarshal(body, &er); err == nil && (er.ErrorMsg != "" || er.ErrorCode != "" || er.InternalErrorCode != "") {
-		return nil, fmt.Errorf("cm %s error (%s/%s): %s", endpoint, strings.TrimSpace(er.ErrorCode), strings.TrimSpace(er.InternalErrorCode), strings.TrimSpace(er.ErrorMsg))
+		return fmt.Errorf("cm %s error (%s/%s): %s", endpoint, strings.TrimSpace(er.ErrorCode), strings.TrimSpace(er.InternalErrorCode), strings.TrimSpace(er.ErrorMsg))
 	}
 
 	var n cmErrorNestedResponse
 	if err := json.Unmarshal(body, &n); err == nil && len(n.Error.Errors) > 0 {
 		first := n.Error.Errors[0]
-		return nil, fmt.Errorf("cm %s error (%s): %s", endpoint, strings.TrimSpace(first.MessageCode), strings.TrimSpace(first.Message))
+		return fmt.Errorf("cm %s error (%s): %s", endpoint, strings.TrimSpace(first.MessageCode), strings.TrimSpace(first.Message))
 	}
 
-	return nil, fmt.Errorf("cm %s: unexpected response: %s", endpoint, strings.TrimSpace(string(body)))
+	return fmt.Errorf("cm %s: unexpected response: %s", endpoint, strings.TrimSpace(string(body)))
 }
 
 func cmGetJSON(
@@ -745,6 +789,7 @@ func getCountriesFlag(cmd *cobra.Command) ([]string, error) {
 	if len(out) == 0 {
 		return nil, fmt.Errorf("no valid countries in --countries")
 	}
+	recordRunCountries(out)
 	return out, nil
 }
 
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const configEnvPrefix = "AADS_ASO_"
+
+// skipConfigAnnotation marks commands that must see flags exactly as given on the
+// command line (config show resolves settings for another command itself).
+const skipConfigAnnotation = "aads-aso/skip-config"
+
+// configSourceAnnotation is set on flags that applyConfig filled from an env var or a
+// config layer, and holds that source. Changed stays reserved for command-line input.
+const configSourceAnnotation = "aads-aso/config-source"
+
+var (
+	configPathFlag string
+	profileFlag    string
+)
+
+// configFile is ~/.aads/config.yaml. Keys are flag names; `defaults` applies to every
+// run and a selected profile is layered on top, each optionally narrowed per command:
+//
+//	default_profile: myapp
+//	defaults:
+//	  timeout: 45s
+//	profiles:
+//	  myapp:
+//	    countries: US,GB
+//	    bundle-id: com.example.app
+//	    cookie-file: ~/.aads/myapp_cookie.txt
+//	    header: ["X-Foo: bar"]
+//	    commands:
+//	      recommend:
+//	        limit: 25
+//
+// Accounts (see configAccount) are selected with --account, or an `account` key in a
+// profile, and sit between the profile and the defaults.
+type configFile struct {
+	DefaultProfile string                   `yaml:"default_profile"`
+	Defaults       configProfile            `yaml:"defaults"`
+	Profiles       map[string]configProfile `yaml:"profiles"`
+	Accounts       map[string]configAccount `yaml:"accounts"`
+}
+
+type configProfile struct {
+	Values   map[string]any            `yaml:",inline"`
+	Commands map[string]map[string]any `yaml:"commands"`
+}
+
+// appIdentityFlags name the app a command works on. Config and env values for them
+// are all-or-nothing: see appIdentitySource.
+var appIdentityFlags = map[string]bool{
+	"adam-id":   true,
+	"app-url":   true,
+	"bundle-id": true,
+	"app-name":  true,
+}
+
+// configLayer is one source of flag values, in precedence order.
+type configLayer struct {
+	source string
+	values map[string]any
+}
+
+// asoConfigSettingRow is the effective value of one flag and where it came from.
+type asoConfigSettingRow struct {
+	Flag   string `json:"flag"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+type asoConfigProfileRow struct {
+	Profile  string   `json:"profile"`
+	Selected bool     `json:"selected"`
+	Settings []string `json:"settings"`
+	Commands []string `json:"commands,omitempty"`
+	Config   string   `json:"config"`
+}
+
+// Flags that select the configuration itself and are never read from it.
+var configMetaFlags = map[string]bool{
+	"config":  true,
+	"profile": true,
+	"help":    true,
+	"version": true,
+}
+
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil || strings.TrimSpace(home) == "" {
+		return ".aads_config.yaml"
+	}
+	return filepath.Join(home, ".aads", "config.yaml")
+}
+
+// loadConfig reads the config file. A missing file is only an error when the path
+// was given explicitly.
+func loadConfig() (*configFile, string, error) {
+	path := strings.TrimSpace(configPathFlag)
+	explicit := path != ""
+	if !explicit {
+		path = strings.TrimSpace(os.Getenv(configEnvPrefix + "CONFIG"))
+		explicit = path != ""
+	}
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	path = expandHome(path)
+
+	cfg := &configFile{}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return cfg, path, nil
+		}
+		return nil, path, fmt.Errorf("read config: %w", err)
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, path, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if err := cfg.validateAccounts(); err != nil {
+		return nil, path, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, path, nil
+}
+
+// selectedProfile returns the profile name from --profile, AADS_ASO_PROFILE or the
+// config's default_profile, in that order.
+func (c *configFile) selectedProfile() (string, *configProfile, error) {
+	name := strings.TrimSpace(profileFlag)
+	if name == "" {
+		name = strings.TrimSpace(os.Getenv(configEnvPrefix + "PROFILE"))
+	}
+	if name == "" {
+		name = strings.TrimSpace(c.DefaultProfile)
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for n := range c.Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return "", nil, fmt.Errorf("profile %q not found (no profiles configured)", name)
+		}
+		return "", nil, fmt.Errorf("profile %q not found (available: %s)", name, strings.Join(names, ", "))
+	}
+	return name, &p, nil
+}
+
+// applyConfig fills flags that were not given on the command line from environment
+// variables (AADS_ASO_<FLAG>), then the selected profile, then the config defaults.
+// It returns the effective value and source of every flag of cmd.
+func applyConfig(cmd *cobra.Command) ([]asoConfigSettingRow, error) {
+	cfg, path, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	profileName, profile, err := cfg.selectedProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	var profileLayers []configLayer
+	if profile != nil {
+		profileLayers = []configLayer{
+			{source: "profile " + profileName + " (" + cmd.Name() + ")", values: profile.Commands[cmd.Name()]},
+			{source: "profile " + profileName, values: profile.Values},
+		}
+	}
+	defaultLayers := []configLayer{
+		{source: "defaults (" + cmd.Name() + ")", values: cfg.Defaults.Commands[cmd.Name()]},
+		{source: "defaults", values: cfg.Defaults.Values},
+	}
+	layers := profileLayers
+	if name := selectedAccountName(cmd, append(profileLayers, defaultLayers...)); name != "" && name != defaultAccountName {
+		if err := validateAccountName(name); err != nil {
+			return nil, fmt.Errorf("--account: %w", err)
+		}
+		layers = append(layers, configLayer{source: "account " + name, values: accountFlagValues(cfg.account(name))})
+	}
+	layers = append(layers, defaultLayers...)
+	appSource := appIdentitySource(cmd, layers)
+
+	var settings []asoConfigSettingRow
+	var applyErr error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if applyErr != nil || configMetaFlags[f.Name] {
+			return
+		}
+		source := "default"
+		switch {
+		case f.Changed:
+			source = "flag"
+		case appIdentityFlags[f.Name] && appSource == "":
+		default:
+			if v, ok := os.LookupEnv(configEnvName(f.Name)); ok && (!appIdentityFlags[f.Name] || appSource == "env") {
+				if err := f.Value.Set(v); err != nil {
+					applyErr = fmt.Errorf("%s: %w", configEnvName(f.Name), err)
+					return
+				}
+				source = "env " + configEnvName(f.Name)
+				setConfigSource(f, source)
+				break
+			}
+			for _, l := range layers {
+				v, ok := l.values[f.Name]
+				if !ok || (appIdentityFlags[f.Name] && l.source != appSource) {
+					continue
+				}
+				if err := setFlagFromConfig(f, v); err != nil {
+					applyErr = fmt.Errorf("config %s, %s: --%s: %w", path, l.source, f.Name, err)
+					return
+				}
+				source = l.source
+				setConfigSource(f, source)
+				break
+			}
+		}
+		value := f.Value.String()
+		if redactedRunFlags[f.Name] && value != "" && value != "[]" {
+			value = "<redacted>"
+		}
+		settings = append(settings, asoConfigSettingRow{Flag: f.Name, Value: value, Source: source})
+	})
+	if applyErr != nil {
+		return nil, applyErr
+	}
+	return settings, nil
+}
+
+// appIdentitySource returns the one source ("env" or a layer) whose app-identifying
+// values apply to cmd, or "" when none do. Config never overrides an app named on the
+// command line (--bundle-id must not lose to an account's adam-id), the values of
+// different sources are not mixed, and commands that only filter by --adam-id
+// (campaigns, auth status) never get it from the config.
+func appIdentitySource(cmd *cobra.Command, layers []configLayer) string {
+	if cmd.Flags().Lookup("app-url") == nil {
+		return ""
+	}
+	for name := range appIdentityFlags {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			return ""
+		}
+	}
+	for name := range appIdentityFlags {
+		if cmd.Flags().Lookup(name) == nil {
+			continue
+		}
+		if _, ok := os.LookupEnv(configEnvName(name)); ok {
+			return "env"
+		}
+	}
+	for _, l := range layers {
+		for name := range appIdentityFlags {
+			if _, ok := l.values[name]; ok && cmd.Flags().Lookup(name) != nil {
+				return l.source
+			}
+		}
+	}
+	return ""
+}
+
+// selectedAccountName resolves --account the same way applyConfig resolves any flag,
+// because the account's layer has to be known before the other flags are filled.
+func selectedAccountName(cmd *cobra.Command, layers []configLayer) string {
+	if f := cmd.Flags().Lookup("account"); f != nil && f.Changed {
+		return strings.TrimSpace(f.Value.String())
+	}
+	if v, ok := os.LookupEnv(configEnvName("account")); ok {
+		return strings.TrimSpace(v)
+	}
+	for _, l := range layers {
+		if v, ok := l.values["account"]; ok && v != nil {
+			return strings.TrimSpace(fmt.Sprint(v))
+		}
+	}
+	return ""
+}
+
+func configEnvName(flag string) string {
+	return configEnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+func setFlagFromConfig(f *pflag.Flag, v any) error {
+	var items []string
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		for _, it := range t {
+			items = append(items, expandHome(fmt.Sprint(it)))
+		}
+	default:
+		items = []string{expandHome(fmt.Sprint(t))}
+	}
+
+	if sv, ok := f.Value.(pflag.SliceValue); ok {
+		return sv.Replace(items)
+	}
+	return f.Value.Set(strings.Join(items, ","))
+}
+
+func setConfigSource(f *pflag.Flag, source string) {
+	if f.Annotations == nil {
+		f.Annotations = map[string][]string{}
+	}
+	f.Annotations[configSourceAnnotation] = []string{source}
+}
+
+// flagConfigSource returns the env var or config layer that filled f, or "" when f
+// kept its default or was given on the command line.
+func flagConfigSource(f *pflag.Flag) string {
+	if f.Changed || len(f.Annotations[configSourceAnnotation]) == 0 {
+		return ""
+	}
+	return f.Annotations[configSourceAnnotation][0]
+}
+
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return p
+	}
+	return filepath.Join(home, strings.TrimPrefix(p, "~"))
+}
+
+func newASOConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the config file and named profiles",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:         "show [COMMAND...]",
+		Short:       "Show the effective settings (and where they come from) for a command",
+		Annotations: map[string]string{skipConfigAnnotation: "true"},
+		Example: "  aads-aso config show popscore --profile myapp\n" +
+			"  AADS_ASO_TIMEOUT=1m aads-aso config show recommend -o table",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target := rootCmd
+			if len(args) > 0 {
+				found, rest, err := rootCmd.Find(args)
+				if err != nil {
+					return err
+				}
+				if len(rest) > 0 {
+					return fmt.Errorf("unknown command %q", strings.Join(args, " "))
+				}
+				target = found
+			}
+			// Merges the persistent flags of parent commands into target.Flags().
+			_ = target.InheritedFlags()
+
+			settings, err := applyConfig(target)
+			if err != nil {
+				return err
+			}
+			return printOutput(settings)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "profiles",
+		Short: "List the profiles defined in the config file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, path, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			selected, _, _ := cfg.selectedProfile()
+
+			var out []asoConfigProfileRow
+			for name, p := range cfg.Profiles {
+				row := asoConfigProfileRow{Profile: name, Selected: name == selected, Config: path}
+				for k := range p.Values {
+					row.Settings = append(row.Settings, k)
+				}
+				for k := range p.Commands {
+					row.Commands = append(row.Commands, k)
+				}
+				sort.Strings(row.Settings)
+				sort.Strings(row.Commands)
+				out = append(out, row)
+			}
+			sort.Slice(out, func(i, j int) bool { return out[i].Profile < out[j].Profile })
+			return printOutput(out)
+		},
+	})
+
+	return cmd
+}
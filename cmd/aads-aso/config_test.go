@@ -0,0 +1,302 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// withTestConfig points the config loader at a temporary file for one test.
+func withTestConfig(t *testing.T, yaml string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	oldPath, oldProfile := configPathFlag, profileFlag
+	configPathFlag, profileFlag = path, ""
+	t.Cleanup(func() { configPathFlag, profileFlag = oldPath, oldProfile })
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); strings.HasPrefix(name, configEnvPrefix) {
+			t.Setenv(name, "") // restores the variable after the test
+			os.Unsetenv(name)
+		}
+	}
+}
+
+func newConfigTestCmd() *cobra.Command {
+	cmd := &cobra.Command{Use: "popscore"}
+	cmd.Flags().String("account", "", "")
+	cmd.Flags().String("cookie-file", "/builtin", "")
+	cmd.Flags().Duration("timeout", 30*time.Second, "")
+	cmd.Flags().StringArray("header", nil, "")
+	return cmd
+}
+
+func configSetting(t *testing.T, settings []asoConfigSettingRow, flag string) asoConfigSettingRow {
+	t.Helper()
+	for _, s := range settings {
+		if s.Flag == flag {
+			return s
+		}
+	}
+	t.Fatalf("no setting for --%s", flag)
+	return asoConfigSettingRow{}
+}
+
+func TestApplyConfigPrecedence(t *testing.T) {
+	// Every layer sets cookie-file; each case removes the layers above the expected one.
+	layers := map[string]string{
+		"profile-cmd": "    commands:\n      popscore:\n        cookie-file: /profile-cmd\n",
+		"profile":     "    cookie-file: /profile\n",
+		"account":     "accounts:\n  acc:\n    cookie-file: /account\n",
+		"default-cmd": "  commands:\n    popscore:\n      cookie-file: /defaults-cmd\n",
+		"default":     "  cookie-file: /defaults\n",
+	}
+	build := func(skip ...string) string {
+		has := func(name string) bool {
+			for _, s := range skip {
+				if s == name {
+					return false
+				}
+			}
+			return true
+		}
+		var b strings.Builder
+		// A selected account always provides a cookie file, so lower layers are only
+		// reached without one.
+		b.WriteString("default_profile: p\nprofiles:\n  p:\n")
+		if has("account") {
+			b.WriteString("    account: acc\n")
+		}
+		for _, name := range []string{"profile", "profile-cmd"} {
+			if has(name) {
+				b.WriteString(layers[name])
+			}
+		}
+		if has("account") {
+			b.WriteString(layers["account"])
+		}
+		b.WriteString("defaults:\n  timeout: 1m\n")
+		for _, name := range []string{"default", "default-cmd"} {
+			if has(name) {
+				b.WriteString(layers[name])
+			}
+		}
+		return b.String()
+	}
+
+	tests := []struct {
+		name       string
+		yaml       string
+		env        string
+		flag       string
+		want       string
+		wantSource string
+	}{
+		{"flag wins", build(), "/env", "/flag", "/flag", "flag"},
+		{"env over profile", build(), "/env", "", "/env", "env AADS_ASO_COOKIE_FILE"},
+		{"profile command section", build(), "", "", "/profile-cmd", "profile p (popscore)"},
+		{"profile", build("profile-cmd"), "", "", "/profile", "profile p"},
+		{"account", build("profile-cmd", "profile"), "", "", "/account", "account acc"},
+		{"defaults command section", build("profile-cmd", "profile", "account"), "", "", "/defaults-cmd", "defaults (popscore)"},
+		{"defaults", build("profile-cmd", "profile", "account", "default-cmd"), "", "", "/defaults", "defaults"},
+		{"built-in default", "", "", "", "/builtin", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTestConfig(t, tt.yaml)
+			if tt.env != "" {
+				t.Setenv("AADS_ASO_COOKIE_FILE", tt.env)
+			}
+			cmd := newConfigTestCmd()
+			if tt.flag != "" {
+				if err := cmd.Flags().Set("cookie-file", tt.flag); err != nil {
+					t.Fatal(err)
+				}
+			}
+			settings, err := applyConfig(cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := configSetting(t, settings, "cookie-file")
+			if got.Value != tt.want || got.Source != tt.wantSource {
+				t.Errorf("cookie-file = %q from %q, want %q from %q", got.Value, got.Source, tt.want, tt.wantSource)
+			}
+
+			// Only command-line input counts as Changed; config values carry their source.
+			f := cmd.Flags().Lookup("cookie-file")
+			if f.Changed != (tt.flag != "") {
+				t.Errorf("Changed = %v, want %v", f.Changed, tt.flag != "")
+			}
+			wantConfigSource := tt.wantSource
+			if wantConfigSource == "flag" || wantConfigSource == "default" {
+				wantConfigSource = ""
+			}
+			if got := flagConfigSource(f); got != wantConfigSource {
+				t.Errorf("flagConfigSource = %q, want %q", got, wantConfigSource)
+			}
+		})
+	}
+}
+
+func TestApplyConfigValues(t *testing.T) {
+	withTestConfig(t, `
+defaults:
+  timeout: 45s
+  header: ["X-A: 1", "X-B: 2"]
+profiles:
+  other:
+    timeout: 2m
+`)
+	cmd := newConfigTestCmd()
+	if _, err := applyConfig(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := cmd.Flags().GetDuration("timeout"); d != 45*time.Second {
+		t.Errorf("timeout = %v, want 45s", d)
+	}
+	if h, _ := cmd.Flags().GetStringArray("header"); !reflect.DeepEqual(h, []string{"X-A: 1", "X-B: 2"}) {
+		t.Errorf("header = %q", h)
+	}
+
+	// AADS_ASO_PROFILE selects a profile like --profile.
+	t.Setenv("AADS_ASO_PROFILE", "other")
+	cmd = newConfigTestCmd()
+	if _, err := applyConfig(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := cmd.Flags().GetDuration("timeout"); d != 2*time.Minute {
+		t.Errorf("timeout with profile = %v, want 2m", d)
+	}
+}
+
+func TestApplyConfigAppIdentity(t *testing.T) {
+	const yaml = `
+accounts:
+  acc:
+    adam-id: 111
+profiles:
+  p:
+    account: acc
+    bundle-id: com.example.profile
+`
+	appCmd := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "popscore"}
+		cmd.Flags().String("account", "", "")
+		cmd.Flags().Int64("adam-id", 0, "")
+		cmd.Flags().String("app-url", "", "")
+		cmd.Flags().String("bundle-id", "", "")
+		cmd.Flags().String("app-name", "", "")
+		return cmd
+	}
+	filterCmd := func() *cobra.Command {
+		cmd := &cobra.Command{Use: "campaigns"}
+		cmd.Flags().String("account", "", "")
+		cmd.Flags().Int64("adam-id", 0, "")
+		return cmd
+	}
+
+	tests := []struct {
+		name       string
+		cmd        func() *cobra.Command
+		profile    string
+		env        map[string]string
+		flags      map[string]string
+		wantAdamID string
+		wantBundle string
+		wantSource string // of adam-id
+	}{
+		{"account adam-id", appCmd, "", nil, map[string]string{"account": "acc"}, "111", "", "account acc"},
+		{"flag bundle-id beats account adam-id", appCmd, "", nil, map[string]string{"account": "acc", "bundle-id": "com.example.flag"}, "0", "com.example.flag", "default"},
+		{"flag app-name blocks profile bundle-id", appCmd, "p", nil, map[string]string{"app-name": "Example"}, "0", "", "default"},
+		{"profile bundle-id beats account adam-id", appCmd, "p", nil, nil, "0", "com.example.profile", "default"},
+		{"env bundle-id beats profile and account", appCmd, "p", map[string]string{"AADS_ASO_BUNDLE_ID": "com.example.env"}, nil, "0", "com.example.env", "default"},
+		{"env adam-id", appCmd, "", map[string]string{"AADS_ASO_ADAM_ID": "222"}, nil, "222", "", "env AADS_ASO_ADAM_ID"},
+		{"filter flag never from account", filterCmd, "", nil, map[string]string{"account": "acc"}, "0", "", "default"},
+		{"filter flag never from env", filterCmd, "", map[string]string{"AADS_ASO_ADAM_ID": "222"}, nil, "0", "", "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTestConfig(t, yaml)
+			profileFlag = tt.profile
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cmd := tt.cmd()
+			for k, v := range tt.flags {
+				if err := cmd.Flags().Set(k, v); err != nil {
+					t.Fatal(err)
+				}
+			}
+			settings, err := applyConfig(cmd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := configSetting(t, settings, "adam-id")
+			if got.Value != tt.wantAdamID || got.Source != tt.wantSource {
+				t.Errorf("adam-id = %q from %q, want %q from %q", got.Value, got.Source, tt.wantAdamID, tt.wantSource)
+			}
+			if cmd.Flags().Lookup("bundle-id") != nil {
+				if got, _ := cmd.Flags().GetString("bundle-id"); got != tt.wantBundle {
+					t.Errorf("bundle-id = %q, want %q", got, tt.wantBundle)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	tests := []struct {
+		name, yaml, profile, env, wantErr string
+	}{
+		{"unknown profile", "profiles:\n  a: {}\n", "nope", "", `profile "nope" not found (available: a)`},
+		{"bad value type", "defaults:\n  timeout: soon\n", "", "", "--timeout"},
+		{"bad env value", "", "", "soon", "AADS_ASO_TIMEOUT"},
+		{"malformed yaml", "defaults: [", "", "", "parse config"},
+		{"account outside the accounts dir", "defaults:\n  account: ../../x\n", "", "", `invalid account name "../../x"`},
+		{"account with a separator", "defaults:\n  account: a/b\n", "", "", `invalid account name "a/b"`},
+		{"configured account with a bad name", "accounts:\n  ..: {}\n", "", "", `invalid account name ".."`},
+		{"configured default account", "accounts:\n  default: {}\n", "", "", `"default" is reserved`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTestConfig(t, tt.yaml)
+			profileFlag = tt.profile
+			if tt.env != "" {
+				t.Setenv("AADS_ASO_TIMEOUT", tt.env)
+			}
+			_, err := applyConfig(newConfigTestCmd())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	withTestConfig(t, "")
+	configPathFlag = filepath.Join(t.TempDir(), "missing.yaml")
+	if _, _, err := loadConfig(); err == nil {
+		t.Error("explicit missing config: expected an error")
+	}
+}
+
+func TestApplyConfigDefaultAccount(t *testing.T) {
+	// --account default is the cookie file used without --account, not accounts/default.
+	withTestConfig(t, "")
+	cmd := newConfigTestCmd()
+	if err := cmd.Flags().Set("account", defaultAccountName); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := applyConfig(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := configSetting(t, settings, "cookie-file"); got.Value != "/builtin" || got.Source != "default" {
+		t.Errorf("cookie-file = %q from %q, want the built-in default", got.Value, got.Source)
+	}
+}
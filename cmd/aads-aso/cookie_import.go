@@ -0,0 +1,309 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Cookies are only taken from exports when they would be sent to the keyword API.
+const (
+	cmCookieHost = "app-ads.apple.com"
+	cmCookiePath = "/cm/api/v2/"
+)
+
+// exportedCookie is one cookie read from a browser or extension export.
+type exportedCookie struct {
+	Name    string
+	Value   string
+	Domain  string
+	Path    string
+	Expires time.Time // zero for session cookies
+	// HostOnly cookies are only sent to Domain itself, not to its subdomains
+	// (includeSubdomains FALSE in cookies.txt, hostOnly in JSON exports).
+	HostOnly bool
+}
+
+// cookieHeaderFromExport detects Netscape cookies.txt, HAR and JSON cookie exports
+// (Cookie-Editor/EditThisCookie arrays, Playwright storage state) and builds a Cookie
+// header from the unexpired app-ads.apple.com cookies. ok is false when content is
+// not an export, e.g. already a plain header.
+func cookieHeaderFromExport(content string, now time.Time) (header, format string, ok bool, err error) {
+	cookies, format, ok, err := parseCookieExport(content)
+	if !ok || err != nil {
+		return "", format, ok, err
+	}
+	header = buildCMCookieHeader(cookies, now)
+	if header == "" {
+		return "", format, true, fmt.Errorf("no unexpired %s cookies found in %s export", cmCookieHost, format)
+	}
+	return header, format, true, nil
+}
+
+func parseCookieExport(content string) ([]exportedCookie, string, bool, error) {
+	trimmed := strings.TrimSpace(content)
+	switch {
+	case trimmed == "":
+		return nil, "", false, nil
+	case strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "["):
+		var v any
+		if err := json.Unmarshal([]byte(trimmed), &v); err != nil {
+			return nil, "json", true, fmt.Errorf("parse cookie export: %w", err)
+		}
+		if m, isObject := v.(map[string]any); isObject {
+			if _, isHAR := m["log"].(map[string]any); isHAR {
+				cookies, err := parseHARCookies(m)
+				return cookies, "har", true, err
+			}
+			if arr, isState := m["cookies"].([]any); isState {
+				return parseJSONCookies(arr), "json", true, nil
+			}
+			return nil, "json", true, fmt.Errorf("unrecognized JSON cookie export (expected a HAR file, a cookie array or an object with \"cookies\")")
+		}
+		arr, _ := v.([]any)
+		return parseJSONCookies(arr), "json", true, nil
+	case isNetscapeCookieFile(trimmed):
+		cookies, err := parseNetscapeCookies(trimmed)
+		return cookies, "cookies.txt", true, err
+	}
+	return nil, "", false, nil
+}
+
+func isNetscapeCookieFile(s string) bool {
+	if strings.HasPrefix(s, "# Netscape HTTP Cookie File") || strings.HasPrefix(s, "# HTTP Cookie File") {
+		return true
+	}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || (strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "#HttpOnly_")) {
+			continue
+		}
+		return len(strings.Split(line, "\t")) == 7
+	}
+	return false
+}
+
+// parseNetscapeCookies reads domain, includeSubdomains, path, secure, expires, name, value
+// lines. Lines starting with #HttpOnly_ are cookies; other # lines are comments.
+func parseNetscapeCookies(s string) ([]exportedCookie, error) {
+	var out []exportedCookie
+	for n, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+		} else if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("cookies.txt line %d: expected 7 tab-separated fields, got %d", n+1, len(fields))
+		}
+		c := exportedCookie{Domain: fields[0], Path: fields[2], Name: fields[5], Value: fields[6]}
+		c.HostOnly = !strings.EqualFold(strings.TrimSpace(fields[1]), "TRUE")
+		if secs, err := strconv.ParseInt(strings.TrimSpace(fields[4]), 10, 64); err == nil && secs > 0 {
+			c.Expires = time.Unix(secs, 0)
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// parseJSONCookies reads cookie objects as written by Cookie-Editor, EditThisCookie
+// (expirationDate, session, hostOnly) and Playwright/Puppeteer (expires, -1 for
+// session).
+func parseJSONCookies(arr []any) []exportedCookie {
+	var out []exportedCookie
+	for _, it := range arr {
+		m, _ := it.(map[string]any)
+		if m == nil {
+			continue
+		}
+		c := exportedCookie{
+			Name:   jsonString(m, "name"),
+			Value:  jsonString(m, "value"),
+			Domain: jsonString(m, "domain"),
+			Path:   jsonString(m, "path"),
+		}
+		if c.Name == "" {
+			continue
+		}
+		c.HostOnly, _ = m["hostOnly"].(bool)
+		if session, _ := m["session"].(bool); !session {
+			for _, key := range []string{"expirationDate", "expires", "expiry"} {
+				if secs, ok := m[key].(float64); ok && secs > 0 {
+					sec, frac := math.Modf(secs)
+					c.Expires = time.Unix(int64(sec), int64(frac*1e9))
+					break
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// parseHARCookies collects the cookies sent to and set by app-ads.apple.com, in
+// request order, so the latest value of each cookie wins.
+func parseHARCookies(har map[string]any) ([]exportedCookie, error) {
+	log, _ := har["log"].(map[string]any)
+	entries, _ := log["entries"].([]any)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("HAR file has no entries")
+	}
+
+	var out []exportedCookie
+	for _, e := range entries {
+		entry, _ := e.(map[string]any)
+		req, _ := entry["request"].(map[string]any)
+		resp, _ := entry["response"].(map[string]any)
+		u, err := url.Parse(jsonString(req, "url"))
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		for _, side := range []map[string]any{req, resp} {
+			list, _ := side["cookies"].([]any)
+			for _, it := range list {
+				m, _ := it.(map[string]any)
+				if m == nil || jsonString(m, "name") == "" {
+					continue
+				}
+				c := exportedCookie{
+					Name:   jsonString(m, "name"),
+					Value:  jsonString(m, "value"),
+					Domain: jsonString(m, "domain"),
+					Path:   jsonString(m, "path"),
+				}
+				if c.Domain == "" {
+					// Sent cookies and Set-Cookie without a Domain attribute.
+					c.Domain, c.HostOnly = u.Hostname(), true
+				}
+				if exp := jsonString(m, "expires"); exp != "" {
+					if t, err := time.Parse(time.RFC3339, exp); err == nil {
+						c.Expires = t
+					}
+				}
+				out = append(out, c)
+			}
+		}
+	}
+	return out, nil
+}
+
+func jsonString(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// buildCMCookieHeader keeps the cookies a browser would send to the keyword API and
+// joins them as "name=value; ...". Later cookies with the same name replace earlier
+// ones but keep their position.
+func buildCMCookieHeader(cookies []exportedCookie, now time.Time) string {
+	index := map[string]int{}
+	var kept []exportedCookie
+	for _, c := range cookies {
+		if !cookieMatchesCMHost(c) || (!c.Expires.IsZero() && !c.Expires.After(now)) {
+			continue
+		}
+		if i, seen := index[c.Name]; seen {
+			kept[i] = c
+			continue
+		}
+		index[c.Name] = len(kept)
+		kept = append(kept, c)
+	}
+
+	parts := make([]string, 0, len(kept))
+	for _, c := range kept {
+		parts = append(parts, c.Name+"="+c.Value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// cookieMatchesCMHost reports whether a browser would send c to the keyword API:
+// its domain and path must match cmCookieHost and cmCookiePath (RFC 6265 5.1.3, 5.1.4).
+func cookieMatchesCMHost(c exportedCookie) bool {
+	return cookieDomainMatch(cmCookieHost, c.Domain, c.HostOnly) && cookiePathMatch(cmCookiePath, c.Path)
+}
+
+// cookieDomainMatch matches host against a cookie domain. A leading dot is ignored;
+// host-only cookies need an exact match, others also match subdomains.
+func cookieDomainMatch(host, domain string, hostOnly bool) bool {
+	host = strings.ToLower(host)
+	domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+	if domain == "" {
+		return false
+	}
+	if host == domain {
+		return true
+	}
+	return !hostOnly && strings.HasSuffix(host, "."+domain)
+}
+
+// cookiePathMatch matches a request path against a cookie path: equal, or a prefix
+// that ends at a "/" boundary (/cm matches /cm/api but not /cmx). An empty or
+// relative cookie path is treated as "/".
+func cookiePathMatch(reqPath, cookiePath string) bool {
+	cookiePath = strings.TrimSpace(cookiePath)
+	if !strings.HasPrefix(cookiePath, "/") {
+		cookiePath = "/"
+	}
+	if reqPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(reqPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || reqPath[len(cookiePath)] == '/'
+}
+
+func newASOAuthImportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "Import cookies from a cookies.txt, HAR or JSON cookie export into the cookie file",
+		Long: "Convert a browser/extension session export (Netscape cookies.txt, HAR, or a JSON cookie array) into the Cookie header\n" +
+			"used by popscore/recommend. Only unexpired cookies for app-ads.apple.com are kept.\n" +
+			"Exports can also be passed directly as --cookie-file; importing stores the header (encrypted when a cookie key is set).",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			cookies, format, ok, err := parseCookieExport(string(b))
+			if err != nil {
+				return err
+			}
+			var header string
+			if ok {
+				header = buildCMCookieHeader(cookies, time.Now())
+				if header == "" {
+					return fmt.Errorf("no unexpired %s cookies found in %s export", cmCookieHost, format)
+				}
+			} else {
+				// Not an export; accept a plain Cookie header too.
+				format = "header"
+				header = trimCookieHeader(string(b))
+			}
+
+			out, _ := cmd.Flags().GetString("out")
+			if err := saveCookieFile(out, header, format, cookies); err != nil {
+				return err
+			}
+			logInfo("cookie_saved", fmt.Sprintf("Imported %d cookies (%s) into %s", len(cookieNames(header)), format, out),
+				"path", out, "format", format, "cookies", cookieNames(header))
+			if cookieValue(header, "XSRF-TOKEN-CM") == "" {
+				logWarn("xsrf_missing", "No XSRF-TOKEN-CM cookie in the export; keyword calls may be rejected")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("out", defaultCMCookieFilePath(), "Cookie file to write")
+	return cmd
+}
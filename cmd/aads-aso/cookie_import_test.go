@@ -0,0 +1,174 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var cookieTestNow = time.Unix(1_800_000_000, 0)
+
+func TestParseCookieExportNetscape(t *testing.T) {
+	content := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"# a comment",
+		"",
+		".apple.com\tTRUE\t/\tTRUE\t1900000000\tmyacinfo\tabc",
+		"#HttpOnly_app-ads.apple.com\tFALSE\t/cm\tTRUE\t0\tXSRF-TOKEN-CM\ttok\r",
+		"apple.com\tFALSE\t/\tFALSE\t1900000000\thostonly\tx",
+	}, "\n")
+	cookies, format, ok, err := parseCookieExport(content)
+	if err != nil || !ok || format != "cookies.txt" {
+		t.Fatalf("parseCookieExport = %v, %q, %v", ok, format, err)
+	}
+	want := []exportedCookie{
+		{Name: "myacinfo", Value: "abc", Domain: ".apple.com", Path: "/", Expires: time.Unix(1900000000, 0)},
+		{Name: "XSRF-TOKEN-CM", Value: "tok", Domain: "app-ads.apple.com", Path: "/cm", HostOnly: true},
+		{Name: "hostonly", Value: "x", Domain: "apple.com", Path: "/", Expires: time.Unix(1900000000, 0), HostOnly: true},
+	}
+	if !reflect.DeepEqual(cookies, want) {
+		t.Errorf("cookies =\n%+v\nwant\n%+v", cookies, want)
+	}
+	if got := buildCMCookieHeader(cookies, cookieTestNow); got != "myacinfo=abc; XSRF-TOKEN-CM=tok" {
+		t.Errorf("header = %q", got)
+	}
+
+	// Detected without the header line too.
+	if _, format, ok, _ := parseCookieExport("app-ads.apple.com\tFALSE\t/\tTRUE\t0\ta\tb"); !ok || format != "cookies.txt" {
+		t.Errorf("headerless cookies.txt not detected: %v %q", ok, format)
+	}
+	if _, _, _, err := parseCookieExport("# Netscape HTTP Cookie File\napple.com\tTRUE\t/\n"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("short line: err = %v", err)
+	}
+}
+
+func TestParseCookieExportJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []exportedCookie
+	}{
+		{
+			name: "cookie-editor array",
+			content: `[
+  {"name": "myacinfo", "value": "abc", "domain": ".apple.com", "path": "/", "expirationDate": 1900000000.5, "hostOnly": false, "session": false},
+  {"name": "sess", "value": "s", "domain": "app-ads.apple.com", "path": "/", "expirationDate": 1900000000, "hostOnly": true, "session": true},
+  {"value": "no name"}
+]`,
+			want: []exportedCookie{
+				{Name: "myacinfo", Value: "abc", Domain: ".apple.com", Path: "/", Expires: time.Unix(1900000000, 5e8)},
+				{Name: "sess", Value: "s", Domain: "app-ads.apple.com", Path: "/", HostOnly: true},
+			},
+		},
+		{
+			name:    "playwright storage state",
+			content: `{"cookies": [{"name": "a", "value": "1", "domain": "app-ads.apple.com", "path": "/cm", "expires": -1}], "origins": []}`,
+			want:    []exportedCookie{{Name: "a", Value: "1", Domain: "app-ads.apple.com", Path: "/cm"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cookies, format, ok, err := parseCookieExport(tt.content)
+			if err != nil || !ok || format != "json" {
+				t.Fatalf("parseCookieExport = %v, %q, %v", ok, format, err)
+			}
+			if !reflect.DeepEqual(cookies, tt.want) {
+				t.Errorf("cookies =\n%+v\nwant\n%+v", cookies, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{`{"foo": 1}`, `[{"name": `} {
+		if _, _, ok, err := parseCookieExport(bad); !ok || err == nil {
+			t.Errorf("parseCookieExport(%q) = %v, %v; want an error", bad, ok, err)
+		}
+	}
+}
+
+func TestParseCookieExportHAR(t *testing.T) {
+	content := `{"log": {"entries": [
+  {"request": {"url": "https://app-ads.apple.com/cm/api/v2/campaigns/find",
+               "cookies": [{"name": "myacinfo", "value": "old"}, {"name": "XSRF-TOKEN-CM", "value": "tok"}]},
+   "response": {"cookies": [{"name": "myacinfo", "value": "new", "domain": ".apple.com", "path": "/", "expires": "2030-01-01T00:00:00Z"}]}},
+  {"request": {"url": "https://other.example.com/", "cookies": [{"name": "tracker", "value": "t"}]},
+   "response": {"cookies": []}},
+  {"request": {"url": "::bad", "cookies": [{"name": "skipped", "value": "x"}]}}
+]}}`
+	cookies, format, ok, err := parseCookieExport(content)
+	if err != nil || !ok || format != "har" {
+		t.Fatalf("parseCookieExport = %v, %q, %v", ok, format, err)
+	}
+	want := []exportedCookie{
+		{Name: "myacinfo", Value: "old", Domain: "app-ads.apple.com", HostOnly: true},
+		{Name: "XSRF-TOKEN-CM", Value: "tok", Domain: "app-ads.apple.com", HostOnly: true},
+		{Name: "myacinfo", Value: "new", Domain: ".apple.com", Path: "/", Expires: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "tracker", Value: "t", Domain: "other.example.com", HostOnly: true},
+	}
+	if !reflect.DeepEqual(cookies, want) {
+		t.Errorf("cookies =\n%+v\nwant\n%+v", cookies, want)
+	}
+	if got := buildCMCookieHeader(cookies, cookieTestNow); got != "myacinfo=new; XSRF-TOKEN-CM=tok" {
+		t.Errorf("header = %q", got)
+	}
+
+	if _, _, _, err := parseCookieExport(`{"log": {"entries": []}}`); err == nil {
+		t.Error("empty HAR: expected an error")
+	}
+}
+
+func TestCookieHeaderFromExport(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+		ok, wantErr         bool
+	}{
+		{"plain header", "a=b; c=d", "", false, false},
+		{"expired only", `[{"name": "a", "value": "1", "domain": "app-ads.apple.com", "expirationDate": 1000}]`, "", true, true},
+		{"session cookie kept", `[{"name": "a", "value": "1", "domain": "app-ads.apple.com", "session": true, "expirationDate": 1000}]`, "a=1", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, ok, err := cookieHeaderFromExport(tt.content, cookieTestNow)
+			if got != tt.want || ok != tt.ok || (err != nil) != tt.wantErr {
+				t.Errorf("cookieHeaderFromExport = %q, %v, %v; want %q, %v, err %v", got, ok, err, tt.want, tt.ok, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCookieMatchesCMHost(t *testing.T) {
+	tests := []struct {
+		name string
+		c    exportedCookie
+		want bool
+	}{
+		{"exact host", exportedCookie{Domain: "app-ads.apple.com"}, true},
+		{"exact host, host-only", exportedCookie{Domain: "app-ads.apple.com", HostOnly: true}, true},
+		{"parent domain", exportedCookie{Domain: ".apple.com"}, true},
+		{"parent domain without dot", exportedCookie{Domain: "apple.com"}, true},
+		{"parent domain, host-only", exportedCookie{Domain: "apple.com", HostOnly: true}, false},
+		{"case-insensitive", exportedCookie{Domain: "APP-ADS.Apple.com"}, true},
+		{"sibling host", exportedCookie{Domain: "ads.apple.com"}, false},
+		{"suffix that is not a domain", exportedCookie{Domain: "le.com"}, false},
+		{"lookalike", exportedCookie{Domain: "evilapp-ads.apple.com"}, false},
+		{"subdomain of host", exportedCookie{Domain: "x.app-ads.apple.com"}, false},
+		{"empty domain", exportedCookie{}, false},
+
+		{"root path", exportedCookie{Domain: "app-ads.apple.com", Path: "/"}, true},
+		{"prefix path", exportedCookie{Domain: "app-ads.apple.com", Path: "/cm"}, true},
+		{"prefix path with slash", exportedCookie{Domain: "app-ads.apple.com", Path: "/cm/api/"}, true},
+		{"full path without slash", exportedCookie{Domain: "app-ads.apple.com", Path: "/cm/api/v2"}, true},
+		{"full path", exportedCookie{Domain: "app-ads.apple.com", Path: "/cm/api/v2/"}, true},
+		{"prefix not at a boundary", exportedCookie{Domain: "app-ads.apple.com", Path: "/cm/ap"}, false},
+		{"other path", exportedCookie{Domain: "app-ads.apple.com", Path: "/cmx"}, false},
+		{"longer path", exportedCookie{Domain: "app-ads.apple.com", Path: "/cm/api/v2/keywords"}, false},
+		{"relative path", exportedCookie{Domain: "app-ads.apple.com", Path: "cm"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cookieMatchesCMHost(tt.c); got != tt.want {
+				t.Errorf("cookieMatchesCMHost(%+v) = %v, want %v", tt.c, got, tt.want)
+			}
+		})
+	}
+}
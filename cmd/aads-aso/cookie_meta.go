@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultCookieExpiryWindow = 24 * time.Hour
+
+// cookieMeta is stored next to a cookie file as <file>.meta.json. It records when
+// the cookie was saved and when each cookie expires (names only, never values), since
+// the Cookie header itself carries no expiry.
+type cookieMeta struct {
+	SavedAt time.Time         `json:"savedAt"`
+	Source  string            `json:"source"`
+	Cookies []cookieMetaEntry `json:"cookies"`
+}
+
+type cookieMetaEntry struct {
+	Name    string     `json:"name"`
+	Domain  string     `json:"domain,omitempty"`
+	Path    string     `json:"path,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+func cookieMetaPath(cookieFile string) string {
+	return cookieFile + ".meta.json"
+}
+
+// saveCookieFile writes the Cookie header and its expiry metadata. Without cookie
+// details (e.g. a pasted header) any stale metadata is removed.
+func saveCookieFile(path, header, source string, cookies []exportedCookie) error {
+	if err := writeCookieFile(path, header); err != nil {
+		return err
+	}
+	if len(cookies) == 0 {
+		if err := os.Remove(cookieMetaPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	// Describe exactly the cookies that made it into the header: the last one of each
+	// name, as buildCMCookieHeader keeps it.
+	meta := cookieMeta{SavedAt: time.Now().UTC(), Source: source}
+	index := map[string]int{}
+	for _, c := range cookies {
+		if cookieValue(header, c.Name) != c.Value || !cookieMatchesCMHost(c) {
+			continue
+		}
+		entry := cookieMetaEntry{Name: c.Name, Domain: c.Domain, Path: c.Path}
+		if !c.Expires.IsZero() {
+			exp := c.Expires.UTC()
+			entry.Expires = &exp
+		}
+		if i, seen := index[c.Name]; seen {
+			meta.Cookies[i] = entry
+			continue
+		}
+		index[c.Name] = len(meta.Cookies)
+		meta.Cookies = append(meta.Cookies, entry)
+	}
+	b, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(cookieMetaPath(path), append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write cookie metadata: %w", err)
+	}
+	return nil
+}
+
+// readCookieMeta returns nil when the cookie file has no metadata.
+func readCookieMeta(cookieFile string) (*cookieMeta, error) {
+	b, err := os.ReadFile(cookieMetaPath(cookieFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var meta cookieMeta
+	if err := json.Unmarshal(b, &meta); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", cookieMetaPath(cookieFile), err)
+	}
+	return &meta, nil
+}
+
+// expires returns the earliest expiry among the stored cookies; session cookies
+// (no expiry) are ignored.
+func (m *cookieMeta) expires() (time.Time, string, bool) {
+	var (
+		earliest time.Time
+		name     string
+	)
+	for _, c := range m.Cookies {
+		if c.Expires == nil {
+			continue
+		}
+		if earliest.IsZero() || c.Expires.Before(earliest) {
+			earliest, name = *c.Expires, c.Name
+		}
+	}
+	return earliest, name, !earliest.IsZero()
+}
+
+// storedCookieExpiry reads the earliest expiry recorded for cookieFile, if any.
+func storedCookieExpiry(cookieFile string) (time.Time, string, bool) {
+	meta, err := readCookieMeta(cookieFile)
+	if err != nil {
+		logDebug("cookie_meta_unreadable", err.Error())
+		return time.Time{}, "", false
+	}
+	if meta == nil {
+		return time.Time{}, "", false
+	}
+	return meta.expires()
+}
+
+func addCookieExpiryFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("cookie-expiry-window", defaultCookieExpiryWindow, "Warn when the stored session expires within this window")
+	cmd.Flags().Bool("refresh-expiring", false, "Refresh the session before starting when it expires within --cookie-expiry-window (needs --auto-cookie or --cookie-exec)")
+}
+
+// checkStoredCookieExpiry warns when the cookie saved in cookieFile expires within
+// --cookie-expiry-window. It reports true when the caller should refresh before
+// starting (--refresh-expiring and canRefresh).
+func checkStoredCookieExpiry(cmd *cobra.Command, cookieFile string, canRefresh bool) bool {
+	exp, name, ok := storedCookieExpiry(cookieFile)
+	if !ok {
+		return false
+	}
+	window, _ := cmd.Flags().GetDuration("cookie-expiry-window")
+	left := time.Until(exp)
+	if left > window {
+		return false
+	}
+
+	refresh, _ := cmd.Flags().GetBool("refresh-expiring")
+	if refresh && canRefresh {
+		return true
+	}
+	if left <= 0 {
+		warnRun("cookie_expiring", fmt.Sprintf("Stored session cookie %s expired at %s; expect a refresh prompt", name, exp.Local().Format(time.RFC3339)),
+			"cookie", name, "expires", exp, "path", cookieFile)
+	} else {
+		warnRun("cookie_expiring", fmt.Sprintf("Stored session cookie %s expires in %s (%s); use --refresh-expiring to refresh before starting", name, left.Round(time.Minute), exp.Local().Format(time.RFC3339)),
+			"cookie", name, "expires", exp, "path", cookieFile)
+	}
+	return false
+}
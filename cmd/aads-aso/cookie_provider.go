@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Why a cookie is requested from a provider. Exec providers see it as
+// $AADS_ASO_COOKIE_REASON, so a secrets-manager wrapper can rotate on "expired".
+const (
+	cookieReasonMissing  = "missing"  // first lookup; earlier providers had nothing
+	cookieReasonExpiring = "expiring" // the stored cookie expires within --cookie-expiry-window
+	cookieReasonExpired  = "expired"  // Apple rejected the cookie in use
+)
+
+// Cookie provider names accepted by --cookie-providers.
+const (
+	cookieProviderStatic  = "static"
+	cookieProviderFile    = "file"
+	cookieProviderExec    = "exec"
+	cookieProviderBrowser = "browser"
+)
+
+const cookieExecTimeout = 2 * time.Minute
+
+var defaultCookieProviders = []string{cookieProviderStatic, cookieProviderFile, cookieProviderExec, cookieProviderBrowser}
+
+// errCookieExpiring is returned with a still-valid cookie when it should be replaced
+// before starting (--refresh-expiring). Later providers are asked for a fresh one.
+var errCookieExpiring = errors.New("stored cookie expires soon")
+
+// errNoCookie is returned by cookieChain.Cookie when no provider has a cookie.
+var errNoCookie = errors.New("no Apple Ads cookie found")
+
+// CookieProvider supplies the Cookie header for app-ads.apple.com. It returns ""
+// without an error when it has nothing to offer, so the next provider is tried.
+type CookieProvider interface {
+	Name() string
+	Cookie(ctx context.Context, reason string) (string, error)
+}
+
+// staticCookieProvider returns the --cookie value.
+type staticCookieProvider struct {
+	value string
+}
+
+func (p staticCookieProvider) Name() string { return cookieProviderStatic }
+
+func (p staticCookieProvider) Cookie(ctx context.Context, reason string) (string, error) {
+	return trimCookieHeader(p.value), nil
+}
+
+// fileCookieProvider reads --cookie-file (plain, encrypted or a browser export).
+type fileCookieProvider struct {
+	cmd          *cobra.Command
+	path         string
+	refreshEarly bool // a later provider can replace an expiring cookie
+}
+
+func (p fileCookieProvider) Name() string { return cookieProviderFile }
+
+func (p fileCookieProvider) Cookie(ctx context.Context, reason string) (string, error) {
+	if strings.TrimSpace(p.path) == "" {
+		return "", nil
+	}
+	cookie, err := readCookieFile(p.path)
+	if err != nil || cookie == "" {
+		return "", err
+	}
+	if reason == cookieReasonMissing && checkStoredCookieExpiry(p.cmd, p.path, p.refreshEarly) {
+		return cookie, errCookieExpiring
+	}
+	return cookie, nil
+}
+
+// execCookieProvider runs --cookie-exec through the shell and reads the cookie from
+// its stdout, e.g. a wrapper around a secrets manager. Plain headers and browser
+// exports are accepted.
+type execCookieProvider struct {
+	command string
+}
+
+func (p execCookieProvider) Name() string { return cookieProviderExec }
+
+func (p execCookieProvider) Cookie(ctx context.Context, reason string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, cookieExecTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", p.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", p.command)
+	}
+	cmd.Env = append(os.Environ(), configEnvPrefix+"COOKIE_REASON="+reason)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	logDebug("cookie_exec", "Running cookie command", "reason", reason)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("--cookie-exec failed: %w\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	header, _, isExport, err := cookieHeaderFromExport(stdout.String(), time.Now())
+	if err != nil {
+		return "", fmt.Errorf("--cookie-exec output: %w", err)
+	}
+	if isExport {
+		return header, nil
+	}
+	return trimCookieHeader(stdout.String()), nil
+}
+
+// browserCookieProvider logs in through a browser (--browser-driver) and saves the
+// result to --cookie-file.
+type browserCookieProvider struct {
+	cmd *cobra.Command
+}
+
+func (p browserCookieProvider) Name() string { return cookieProviderBrowser }
+
+func (p browserCookieProvider) Cookie(ctx context.Context, reason string) (string, error) {
+	switch reason {
+	case cookieReasonExpiring:
+		logInfo("cookie_refresh_started", "Session cookie is about to expire. Launching browser to refresh session...", "reason", reason)
+	case cookieReasonExpired:
+		logInfo("cookie_refresh_started", "Cookie appears expired. Launching browser to refresh session...", "reason", reason)
+	default:
+		logInfo("cookie_refresh_started", "Cookie not found. Launching browser to refresh session...", "reason", reason)
+	}
+	return refreshCMCookieFromFlags(ctx, p.cmd)
+}
+
+// cookieChain asks providers in --cookie-providers order and remembers the cookie in
+// use, so a refresh after Apple rejects it only accepts one not rejected before.
+type cookieChain struct {
+	providers []CookieProvider
+	current   string
+	provider  string // name of the provider of current
+	rejected  map[string]bool
+}
+
+func addCookieProviderFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cookie-exec", "", "Shell command that prints the Cookie header (or a cookie export), e.g. a secrets manager wrapper; gets $AADS_ASO_COOKIE_REASON")
+	cmd.Flags().StringSlice("cookie-providers", defaultCookieProviders, "Order in which cookie sources are tried: static (--cookie), file (--cookie-file), exec (--cookie-exec), browser (--auto-cookie)")
+}
+
+// newCookieChainFromFlags builds the providers named in --cookie-providers. Providers
+// without configuration (no --cookie-exec, --auto-cookie=false) are left out.
+func newCookieChainFromFlags(cmd *cobra.Command) (*cookieChain, error) {
+	names, _ := cmd.Flags().GetStringSlice("cookie-providers")
+	cookie, _ := cmd.Flags().GetString("cookie")
+	cookieFile, _ := cmd.Flags().GetString("cookie-file")
+	command, _ := cmd.Flags().GetString("cookie-exec")
+	autoCookie, _ := cmd.Flags().GetBool("auto-cookie")
+
+	chain := &cookieChain{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch name {
+		case cookieProviderStatic:
+			chain.providers = append(chain.providers, staticCookieProvider{value: cookie})
+		case cookieProviderFile:
+			chain.providers = append(chain.providers, fileCookieProvider{cmd: cmd, path: cookieFile})
+		case cookieProviderExec:
+			if strings.TrimSpace(command) != "" {
+				chain.providers = append(chain.providers, execCookieProvider{command: command})
+			}
+		case cookieProviderBrowser:
+			if autoCookie {
+				chain.providers = append(chain.providers, browserCookieProvider{cmd: cmd})
+			}
+		default:
+			return nil, fmt.Errorf("invalid --cookie-providers entry %q (expected static, file, exec or browser)", name)
+		}
+	}
+
+	// The file provider only asks for an early refresh when someone can deliver one.
+	for i, p := range chain.providers {
+		if fp, ok := p.(fileCookieProvider); ok {
+			fp.refreshEarly = chain.canRefreshAfter(i)
+			chain.providers[i] = fp
+		}
+	}
+	return chain, nil
+}
+
+// canRefreshAfter reports whether a provider after index i can produce a new cookie.
+func (c *cookieChain) canRefreshAfter(i int) bool {
+	for _, p := range c.providers[i+1:] {
+		switch p.(type) {
+		case execCookieProvider, browserCookieProvider:
+			return true
+		}
+	}
+	return false
+}
+
+// canRefresh reports whether a rejected cookie can be replaced at all.
+func (c *cookieChain) canRefresh() bool {
+	return c.canRefreshAfter(-1)
+}
+
+func (c *cookieChain) names() string {
+	names := make([]string, 0, len(c.providers))
+	for _, p := range c.providers {
+		names = append(names, p.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// Cookie returns the first cookie any provider has.
+func (c *cookieChain) Cookie(ctx context.Context) (string, error) {
+	reason := cookieReasonMissing
+	expiring, expiringProvider := "", ""
+	for _, p := range c.providers {
+		cookie, err := p.Cookie(ctx, reason)
+		if errors.Is(err, errCookieExpiring) {
+			reason, expiring, expiringProvider = cookieReasonExpiring, cookie, p.Name()
+			continue
+		}
+		if err != nil && expiring != "" {
+			// The expiring cookie still works; a failed early refresh must not stop the run.
+			logWarn("cookie_refresh_failed", fmt.Sprintf("Early cookie refresh by provider %s failed (%v); using the expiring cookie", p.Name(), err),
+				"provider", p.Name(), "error", err.Error())
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("cookie provider %s: %w", p.Name(), err)
+		}
+		if cookie != "" {
+			logDebug("cookie_provider", "Using cookie from provider "+p.Name(), "provider", p.Name(), "reason", reason)
+			c.current, c.provider = cookie, p.Name()
+			return cookie, nil
+		}
+	}
+	if expiring != "" {
+		c.current, c.provider = expiring, expiringProvider
+		return expiring, nil
+	}
+	return "", fmt.Errorf("%w (tried %s); pass --cookie, --cookie-file or --cookie-exec, or enable --auto-cookie", errNoCookie, c.names())
+}
+
+// Refresh asks the providers again after Apple rejected the current cookie and
+// returns the first cookie that was not rejected yet.
+func (c *cookieChain) Refresh(ctx context.Context) (string, error) {
+	if c.rejected == nil {
+		c.rejected = map[string]bool{}
+	}
+	c.rejected[c.current] = true
+	for _, p := range c.providers {
+		cookie, err := p.Cookie(ctx, cookieReasonExpired)
+		if err != nil && !errors.Is(err, errCookieExpiring) {
+			return "", fmt.Errorf("cookie provider %s: %w", p.Name(), err)
+		}
+		if cookie != "" && !c.rejected[cookie] {
+			logDebug("cookie_provider", "Using refreshed cookie from provider "+p.Name(), "provider", p.Name(), "reason", cookieReasonExpired)
+			c.current, c.provider = cookie, p.Name()
+			return cookie, nil
+		}
+	}
+	return "", fmt.Errorf("Apple Ads rejected the cookie and no cookie provider (%s) supplied a new one", c.names())
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeCookieProvider answers with a fixed cookie and error per reason and records
+// every call as "name:reason".
+type fakeCookieProvider struct {
+	name    string
+	cookies map[string]string
+	errs    map[string]error
+	calls   *[]string
+}
+
+func (p fakeCookieProvider) Name() string { return p.name }
+
+func (p fakeCookieProvider) Cookie(ctx context.Context, reason string) (string, error) {
+	*p.calls = append(*p.calls, p.name+":"+reason)
+	return p.cookies[reason], p.errs[reason]
+}
+
+func TestCookieChainCookie(t *testing.T) {
+	errExec := errors.New("exit status 1")
+	tests := []struct {
+		name         string
+		providers    []fakeCookieProvider
+		want         string
+		wantProvider string
+		wantErr      string
+		wantCalls    []string
+	}{
+		{
+			name: "first provider with a cookie wins",
+			providers: []fakeCookieProvider{
+				{name: "static"},
+				{name: "file", cookies: map[string]string{"missing": "a=file"}},
+				{name: "exec", cookies: map[string]string{"missing": "a=exec"}},
+			},
+			want:         "a=file",
+			wantProvider: "file",
+			wantCalls:    []string{"static:missing", "file:missing"},
+		},
+		{
+			name: "expiring cookie is replaced by a later provider",
+			providers: []fakeCookieProvider{
+				{name: "file", cookies: map[string]string{"missing": "a=old"}, errs: map[string]error{"missing": errCookieExpiring}},
+				{name: "exec", cookies: map[string]string{"expiring": "a=new"}},
+			},
+			want:         "a=new",
+			wantProvider: "exec",
+			wantCalls:    []string{"file:missing", "exec:expiring"},
+		},
+		{
+			name: "expiring cookie is kept when nobody has a new one",
+			providers: []fakeCookieProvider{
+				{name: "static", cookies: map[string]string{"missing": "a=old"}, errs: map[string]error{"missing": errCookieExpiring}},
+				{name: "browser"},
+			},
+			want:         "a=old",
+			wantProvider: "static",
+			wantCalls:    []string{"static:missing", "browser:expiring"},
+		},
+		{
+			name: "failed early refresh falls back to the expiring cookie",
+			providers: []fakeCookieProvider{
+				{name: "file", cookies: map[string]string{"missing": "a=old"}, errs: map[string]error{"missing": errCookieExpiring}},
+				{name: "exec", errs: map[string]error{"expiring": errExec}},
+				{name: "browser"},
+			},
+			want:         "a=old",
+			wantProvider: "file",
+			wantCalls:    []string{"file:missing", "exec:expiring", "browser:expiring"},
+		},
+		{
+			name: "provider error without an expiring cookie",
+			providers: []fakeCookieProvider{
+				{name: "file"},
+				{name: "exec", errs: map[string]error{"missing": errExec}},
+				{name: "browser", cookies: map[string]string{"missing": "a=b"}},
+			},
+			wantErr:   "cookie provider exec: exit status 1",
+			wantCalls: []string{"file:missing", "exec:missing"},
+		},
+		{
+			name:      "no cookie",
+			providers: []fakeCookieProvider{{name: "static"}, {name: "file"}},
+			wantErr:   "no Apple Ads cookie found (tried static,file)",
+			wantCalls: []string{"static:missing", "file:missing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			chain := &cookieChain{}
+			for _, p := range tt.providers {
+				p.calls = &calls
+				chain.providers = append(chain.providers, p)
+			}
+			got, err := chain.Cookie(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || chain.current != tt.want || chain.provider != tt.wantProvider {
+				t.Errorf("Cookie = %q (current %q from %q), want %q from %q", got, chain.current, chain.provider, tt.want, tt.wantProvider)
+			}
+			if !reflect.DeepEqual(calls, tt.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestCookieChainRefresh(t *testing.T) {
+	tests := []struct {
+		name         string
+		providers    []fakeCookieProvider
+		want         string
+		wantProvider string
+		wantErr      string
+	}{
+		{
+			name: "skips the rejected cookie",
+			providers: []fakeCookieProvider{
+				{name: "file", cookies: map[string]string{"missing": "a=old", "expired": "a=old"}},
+				{name: "exec", cookies: map[string]string{"expired": "a=new"}},
+			},
+			want:         "a=new",
+			wantProvider: "exec",
+		},
+		{
+			name: "nobody has a new cookie",
+			providers: []fakeCookieProvider{
+				{name: "file", cookies: map[string]string{"missing": "a=old", "expired": "a=old"}},
+				{name: "browser"},
+			},
+			wantErr: "no cookie provider (file,browser) supplied a new one",
+		},
+		{
+			name: "refresh error",
+			providers: []fakeCookieProvider{
+				{name: "file", cookies: map[string]string{"missing": "a=old", "expired": "a=old"}},
+				{name: "exec", errs: map[string]error{"expired": errors.New("exit status 2")}},
+			},
+			wantErr: "cookie provider exec: exit status 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			chain := &cookieChain{}
+			for _, p := range tt.providers {
+				p.calls = &calls
+				chain.providers = append(chain.providers, p)
+			}
+			if _, err := chain.Cookie(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			got, err := chain.Refresh(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || chain.provider != tt.wantProvider {
+				t.Errorf("Refresh = %q from %q, want %q from %q", got, chain.provider, tt.want, tt.wantProvider)
+			}
+			if !chain.rejected["a=old"] {
+				t.Error("the rejected cookie is not remembered")
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Encrypted cookie files hold encryptedCookiePrefix followed by base64 of
+// salt | nonce | AES-256-GCM ciphertext, with the key derived from a passphrase.
+const (
+	encryptedCookiePrefix = "aads-enc:v1:"
+	cookieKeyEnv          = configEnvPrefix + "COOKIE_KEY"
+	cookieKeySaltSize     = 16
+	cookieKeyIterations   = 210000
+)
+
+var cookieKeyFileFlag string
+
+// cookieEncryptionKey returns the passphrase from $AADS_ASO_COOKIE_KEY or
+// --cookie-key-file, or "" when cookies are stored in plaintext.
+func cookieEncryptionKey() (string, error) {
+	if v := strings.TrimSpace(os.Getenv(cookieKeyEnv)); v != "" {
+		return v, nil
+	}
+	path := strings.TrimSpace(cookieKeyFileFlag)
+	if path == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return "", fmt.Errorf("read cookie key file: %w", err)
+	}
+	key := strings.TrimSpace(string(b))
+	if key == "" {
+		return "", fmt.Errorf("cookie key file %s is empty", path)
+	}
+	return key, nil
+}
+
+// writeCookieFile stores a Cookie header at path, encrypted when a cookie key is
+// configured.
+func writeCookieFile(path, cookie string) error {
+	data := cookie + "\n"
+	key, err := cookieEncryptionKey()
+	if err != nil {
+		return err
+	}
+	if key != "" {
+		enc, err := encryptCookie(cookie, key)
+		if err != nil {
+			return err
+		}
+		data = enc + "\n"
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create cookie file dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		return fmt.Errorf("write cookie file: %w", err)
+	}
+	return nil
+}
+
+// decodeCookieFile turns the contents of a cookie file into a Cookie header,
+// decrypting it when needed.
+func decodeCookieFile(path, content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, encryptedCookiePrefix) {
+		return content, nil
+	}
+	key, err := cookieEncryptionKey()
+	if err != nil {
+		return "", err
+	}
+	if key == "" {
+		return "", fmt.Errorf("cookie file %s is encrypted; set %s or --cookie-key-file", path, cookieKeyEnv)
+	}
+	cookie, err := decryptCookie(content, key)
+	if err != nil {
+		return "", fmt.Errorf("decrypt cookie file %s: %w", path, err)
+	}
+	return cookie, nil
+}
+
+func encryptCookie(cookie, passphrase string) (string, error) {
+	salt := make([]byte, cookieKeySaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	aead, err := cookieCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	out := append(salt, nonce...)
+	out = aead.Seal(out, nonce, []byte(cookie), []byte(encryptedCookiePrefix))
+	return encryptedCookiePrefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+func decryptCookie(content, passphrase string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(content, encryptedCookiePrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encoding: %w", err)
+	}
+	if len(raw) < cookieKeySaltSize {
+		return "", fmt.Errorf("truncated data")
+	}
+	salt, rest := raw[:cookieKeySaltSize], raw[cookieKeySaltSize:]
+	aead, err := cookieCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+	if len(rest) < aead.NonceSize() {
+		return "", fmt.Errorf("truncated data")
+	}
+	nonce, sealed := rest[:aead.NonceSize()], rest[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(encryptedCookiePrefix))
+	if err != nil {
+		return "", fmt.Errorf("wrong key or corrupted file")
+	}
+	return string(plain), nil
+}
+
+func cookieCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, cookieKeyIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptCookieRoundTrip(t *testing.T) {
+	tests := []struct {
+		name, cookie, key string
+	}{
+		{"header", "myacinfo=abc; XSRF-TOKEN-CM=tok", "correct horse"},
+		{"empty", "", "k"},
+		{"unicode key", "a=b", "pässwörd 🔑"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := encryptCookie(tt.cookie, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(enc, encryptedCookiePrefix) {
+				t.Fatalf("missing prefix: %q", enc)
+			}
+			if tt.cookie != "" && strings.Contains(enc, tt.cookie) {
+				t.Fatalf("ciphertext contains the cookie: %q", enc)
+			}
+			got, err := decryptCookie(enc, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.cookie {
+				t.Errorf("round trip = %q, want %q", got, tt.cookie)
+			}
+		})
+	}
+
+	// Fresh salt and nonce every time.
+	a, _ := encryptCookie("a=b", "k")
+	b, _ := encryptCookie("a=b", "k")
+	if a == b {
+		t.Error("two encryptions of the same cookie are identical")
+	}
+}
+
+func TestDecryptCookieErrors(t *testing.T) {
+	enc, err := encryptCookie("a=b", "right")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(enc, encryptedCookiePrefix))
+	tampered := append([]byte(nil), raw...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name, content, key, wantErr string
+	}{
+		{"wrong key", enc, "wrong", "wrong key or corrupted file"},
+		{"tampered", encryptedCookiePrefix + base64.StdEncoding.EncodeToString(tampered), "right", "wrong key or corrupted file"},
+		{"bad base64", encryptedCookiePrefix + "!!!", "right", "invalid encoding"},
+		{"no salt", encryptedCookiePrefix + base64.StdEncoding.EncodeToString([]byte("short")), "right", "truncated data"},
+		{"no nonce", encryptedCookiePrefix + base64.StdEncoding.EncodeToString(raw[:cookieKeySaltSize+4]), "right", "truncated data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decryptCookie(tt.content, tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCookieFileEncryption(t *testing.T) {
+	oldKeyFile := cookieKeyFileFlag
+	t.Cleanup(func() { cookieKeyFileFlag = oldKeyFile })
+	cookieKeyFileFlag = ""
+	path := filepath.Join(t.TempDir(), "cookie.txt")
+
+	t.Setenv(cookieKeyEnv, "secret")
+	if err := writeCookieFile(path, "a=b; c=d"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(b), encryptedCookiePrefix) {
+		t.Fatalf("cookie file is not encrypted: %q", b)
+	}
+	if got, err := decodeCookieFile(path, string(b)); err != nil || got != "a=b; c=d" {
+		t.Errorf("decode = %q, %v", got, err)
+	}
+
+	t.Setenv(cookieKeyEnv, "other")
+	if _, err := decodeCookieFile(path, string(b)); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("decode with another key: err = %v", err)
+	}
+
+	t.Setenv(cookieKeyEnv, "")
+	if _, err := decodeCookieFile(path, string(b)); err == nil || !strings.Contains(err.Error(), "is encrypted") {
+		t.Errorf("decode without a key: err = %v", err)
+	}
+	if got, err := decodeCookieFile(path, " a=b \n"); err != nil || got != "a=b" {
+		t.Errorf("plaintext decode = %q, %v", got, err)
+	}
+
+	// The key file is used when the env var is unset.
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cookieKeyFileFlag = keyFile
+	if got, err := decodeCookieFile(path, string(b)); err != nil || got != "a=b; c=d" {
+		t.Errorf("decode with key file = %q, %v", got, err)
+	}
+}
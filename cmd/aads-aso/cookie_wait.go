@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// How cookie refresh waits for the user to be logged in.
+const (
+	loginModeAuto   = "auto"   // prompt when stdin is a terminal, poll otherwise
+	loginModePrompt = "prompt" // wait for Enter
+	loginModePoll   = "poll"   // wait until the session cookies appear (CI)
+)
+
+const (
+	defaultLoginTimeout  = 2 * time.Minute
+	loginPollInterval    = 2 * time.Second
+	loginRequiredDefault = "XSRF-TOKEN-CM,myacinfo"
+)
+
+// cmLoginState is what a browser driver sees while waiting for login.
+type cmLoginState struct {
+	URL       string
+	SignIn    bool // an Apple sign-in frame/page is showing
+	TwoFactor bool // the sign-in page asks for a verification code
+	Cookies   []exportedCookie
+}
+
+func addLoginWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().String("login-mode", loginModeAuto, "How to wait for login: prompt (press Enter), poll (until the session cookies appear; for CI), or auto (prompt on a terminal, else poll)")
+	cmd.Flags().Duration("login-timeout", defaultLoginTimeout, "How long --login-mode poll waits for the session cookies")
+	cmd.Flags().StringSlice("login-cookies", strings.Split(loginRequiredDefault, ","), "Cookies that must be present before --login-mode poll exports the session")
+}
+
+// getLoginWaitFlags resolves --login-mode auto to prompt or poll.
+func getLoginWaitFlags(cmd *cobra.Command) (mode string, timeout time.Duration, required []string, err error) {
+	mode, _ = cmd.Flags().GetString("login-mode")
+	timeout, _ = cmd.Flags().GetDuration("login-timeout")
+	required, _ = cmd.Flags().GetStringSlice("login-cookies")
+
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "", loginModeAuto:
+		mode = loginModePoll
+		if stdinIsTerminal() {
+			mode = loginModePrompt
+		}
+	case loginModePrompt, loginModePoll:
+	default:
+		return "", 0, nil, fmt.Errorf("invalid --login-mode %q (expected auto, prompt or poll)", mode)
+	}
+	return mode, timeout, required, nil
+}
+
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func promptForLogin(url string) {
+	logInfo("login_wait", "Browser opened. Complete Apple Ads login in the browser window.", "url", url)
+	// The prompt is written directly so it shows even with --quiet.
+	fmt.Fprintln(os.Stderr, "When you are logged in, press Enter here to export cookies...")
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+}
+
+// waitForCMLogin polls the browser until all required cookies are present, which
+// happens without user input when the persistent profile still holds a login. It
+// fails early when Apple asks for a two-factor code, since nobody can enter it.
+func waitForCMLogin(ctx context.Context, opts cmCookieRefreshOptions, probe func(context.Context) (cmLoginState, error)) ([]exportedCookie, error) {
+	timeout := opts.LoginTimeout
+	if timeout <= 0 {
+		timeout = defaultLoginTimeout
+	}
+	required := opts.LoginCookies
+	if len(required) == 0 {
+		required = strings.Split(loginRequiredDefault, ",")
+	}
+	logInfo("login_wait", fmt.Sprintf("Waiting up to %s for the browser session (%s)...", timeout, strings.Join(required, ", ")),
+		"timeout", timeout, "cookies", required)
+
+	// Probes share the deadline, so a hanging browser call cannot outlast it.
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	tick := time.NewTicker(loginPollInterval)
+	defer tick.Stop()
+
+	var (
+		last    cmLoginState
+		lastErr error
+		missing = required
+	)
+	for {
+		state, err := probe(waitCtx)
+		switch {
+		case err != nil && waitCtx.Err() != nil:
+			// Cut off by the deadline; report what earlier probes saw, if any.
+			if lastErr == nil && last.URL == "" {
+				lastErr = err
+			}
+		case err != nil:
+			lastErr = err
+			logDebug("login_probe_failed", err.Error())
+		default:
+			last, lastErr = state, nil
+			missing = missingLoginCookies(state.Cookies, required, time.Now())
+			if len(missing) == 0 {
+				logInfo("login_recognized", "Browser session recognized", "url", state.URL)
+				return state.Cookies, nil
+			}
+			if state.TwoFactor {
+				return nil, fmt.Errorf("Apple is asking for a two-factor verification code, which cannot be entered in --login-mode poll; %s", loginPersistHint(opts))
+			}
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			switch {
+			case last.SignIn:
+				return nil, fmt.Errorf("still on the Apple sign-in page after %s (no saved login in the browser profile, or it expired); %s", timeout, loginPersistHint(opts))
+			case lastErr != nil:
+				return nil, fmt.Errorf("session cookies not found after %s: %w", timeout, lastErr)
+			default:
+				return nil, fmt.Errorf("session cookies %s did not appear within %s (page %s); %s", strings.Join(missing, ", "), timeout, last.URL, loginPersistHint(opts))
+			}
+		case <-tick.C:
+		}
+	}
+}
+
+func missingLoginCookies(cookies []exportedCookie, required []string, now time.Time) []string {
+	header := buildCMCookieHeader(cookies, now)
+	var missing []string
+	for _, name := range required {
+		name = strings.TrimSpace(name)
+		if name != "" && cookieValue(header, name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func loginPersistHint(opts cmCookieRefreshOptions) string {
+	return fmt.Sprintf("log in once interactively to save the session in the profile: aads-aso cm-cookie --login-mode prompt --profile-dir %s", opts.ProfileDir)
+}
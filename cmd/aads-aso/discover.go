@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type asoDiscoverRow struct {
+	Country    string  `json:"country"`
+	Term       string  `json:"term"`
+	Rank       int     `json:"rank"`
+	Priority   *int    `json:"priority,omitempty"`
+	Popularity *int    `json:"popularity,omitempty"`
+	MatchType  *string `json:"matchType,omitempty"`
+	Found      bool    `json:"found"`
+	Source     string  `json:"source"`
+}
+
+func newASODiscoverCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "discover",
+		Short: "Autocomplete suggestions enriched with popularity (hints + popscore in one call)",
+		Long: "Collect App Store autocomplete suggestions via MZSearchHints, then score them with the Apple Ads popularity endpoint for the same countries.\n" +
+			"If no usable session cookie is available, suggestions are still returned without popularity.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			countries, err := getCountriesFlag(cmd)
+			if err != nil {
+				return err
+			}
+
+			query, _ := cmd.Flags().GetString("query")
+			query = strings.TrimSpace(query)
+			if query == "" {
+				return fmt.Errorf("--query is required")
+			}
+
+			recordRunSeed(query)
+			opts := getMZSearchHintsOptions(cmd)
+
+			// The session is only opened once a country has suggestions to score.
+			var (
+				session       *cmSession
+				sessionOpened bool
+				out           []asoDiscoverRow
+			)
+			for _, cc := range countries {
+				done := trackRunCountry(cc)
+				hints, err := fetchMZSearchHints(ctx, opts.Storefront, opts.ClientApp, opts.Media, cc, query, opts.E)
+				if err != nil {
+					done(err)
+					return err
+				}
+				if len(hints) > opts.Limit {
+					hints = hints[:opts.Limit]
+				}
+				if len(hints) == 0 {
+					done(nil)
+					continue
+				}
+
+				if !sessionOpened {
+					sessionOpened = true
+					if session, err = newCMSessionFromFlags(ctx, cmd, countries); err != nil {
+						warnRun("popularity_unavailable", fmt.Sprintf("Popularity unavailable (%v); returning suggestions only", err), "error", err.Error())
+					}
+				}
+
+				var byName map[string]cmKeywordItem
+				if session != nil {
+					terms := make([]string, 0, len(hints))
+					for _, it := range hints {
+						terms = append(terms, it.Term)
+					}
+					items, err := session.popularities(ctx, cc, terms)
+					if err != nil {
+						warnRun("popularity_unavailable", fmt.Sprintf("Popularity unavailable for %s (%v); returning suggestions only", cc, err), "country", cc, "error", err.Error())
+					} else {
+						byName = map[string]cmKeywordItem{}
+						for _, it := range items {
+							byName[normKeyword(it.Name)] = it
+						}
+					}
+				}
+				done(nil)
+
+				for i, it := range hints {
+					row := asoDiscoverRow{
+						Country:  cc,
+						Term:     it.Term,
+						Rank:     i + 1,
+						Priority: it.Priority,
+						Source:   "mzsearchhints",
+					}
+					if byName != nil {
+						row.Source = "mzsearchhints+cm_api_v2"
+						if item, ok := byName[normKeyword(it.Term)]; ok {
+							row.Found = true
+							row.Popularity, row.MatchType = cmItemPopularity(item)
+						}
+					}
+					out = append(out, row)
+				}
+			}
+
+			return printOutput(out)
+		},
+	}
+
+	// Without a cookie, discover degrades to suggestions only instead of waiting for a
+	// browser login; --auto-cookie (flag, env or config) turns the browser back on.
+	addCommonCMKeywordFlags(cmd, false)
+	addMZSearchHintsFlags(cmd)
+	return cmd
+}
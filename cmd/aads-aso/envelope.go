@@ -0,0 +1,63 @@
+package main
+
+import (
+	"time"
+)
+
+var envelopeFlag bool
+
+// runEnvelope is what --envelope prints for json and yaml output: the rows plus the
+// run metadata that would otherwise only be visible on stderr.
+type runEnvelope struct {
+	Run  runEnvelopeMeta `json:"run" yaml:"run"`
+	Rows any             `json:"rows" yaml:"rows"`
+}
+
+type runEnvelopeMeta struct {
+	ID           string             `json:"id" yaml:"id"`
+	Command      string             `json:"command" yaml:"command"`
+	Version      string             `json:"version" yaml:"version"`
+	StartedAt    string             `json:"startedAt" yaml:"startedAt"`
+	FinishedAt   string             `json:"finishedAt" yaml:"finishedAt"`
+	DurationMS   int64              `json:"durationMs" yaml:"durationMs"`
+	AdamID       int64              `json:"adamId,omitempty" yaml:"adamId,omitempty"`
+	AdamIDSource string             `json:"adamIdSource,omitempty" yaml:"adamIdSource,omitempty"`
+	AppName      string             `json:"appName,omitempty" yaml:"appName,omitempty"`
+	Countries    []string           `json:"countries,omitempty" yaml:"countries,omitempty"`
+	Storefront   string             `json:"storefront,omitempty" yaml:"storefront,omitempty"`
+	Seed         string             `json:"seed,omitempty" yaml:"seed,omitempty"`
+	RowCount     int                `json:"rowCount" yaml:"rowCount"`
+	Timings      []runCountryTiming `json:"timings,omitempty" yaml:"timings,omitempty"`
+	Warnings     []string           `json:"warnings" yaml:"warnings"`
+	Flags        []runFlag          `json:"flags,omitempty" yaml:"flags,omitempty"`
+}
+
+func newRunEnvelope(data any) runEnvelope {
+	finished := time.Now().UTC()
+	_, records, _ := collectRowRecords(data)
+	warnings := currentRun.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	return runEnvelope{
+		Run: runEnvelopeMeta{
+			ID:           currentRun.ID,
+			Command:      currentRun.Command,
+			Version:      version,
+			StartedAt:    currentRun.StartedAt.Format(time.RFC3339),
+			FinishedAt:   finished.Format(time.RFC3339),
+			DurationMS:   finished.Sub(currentRun.StartedAt).Milliseconds(),
+			AdamID:       currentRun.AdamID,
+			AdamIDSource: currentRun.AdamIDSource,
+			AppName:      currentRun.AppName,
+			Countries:    currentRun.Countries,
+			Storefront:   currentRun.Storefront,
+			Seed:         currentRun.Seed,
+			RowCount:     len(records),
+			Timings:      currentRun.Timings,
+			Warnings:     warnings,
+			Flags:        currentRun.Flags,
+		},
+		Rows: data,
+	}
+}
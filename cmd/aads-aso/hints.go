@@ -41,39 +41,19 @@ func newASOHintsCmd() *cobra.Command {
 				return fmt.Errorf("--query is required")
 			}
 
-			limit, _ := cmd.Flags().GetInt("limit")
-			if limit <= 0 {
-				limit = 20
-			}
-
-			storefront, _ := cmd.Flags().GetString("storefront")
-			storefront = strings.TrimSpace(storefront)
-			if storefront == "" {
-				storefront = "143441-1,29 t:native"
-			}
-
-			clientApp, _ := cmd.Flags().GetString("client-application")
-			clientApp = strings.TrimSpace(clientApp)
-			if clientApp == "" {
-				clientApp = "Software"
-			}
-
-			media, _ := cmd.Flags().GetString("media")
-			media = strings.TrimSpace(media)
-			if media == "" {
-				media = "software"
-			}
-
-			eFlag, _ := cmd.Flags().GetBool("e")
+			recordRunSeed(query)
+			opts := getMZSearchHintsOptions(cmd)
 
 			var out []asoHintRow
 			for _, cc := range countries {
-				terms, err := fetchMZSearchHints(ctx, storefront, clientApp, media, cc, query, eFlag)
+				done := trackRunCountry(cc)
+				terms, err := fetchMZSearchHints(ctx, opts.Storefront, opts.ClientApp, opts.Media, cc, query, opts.E)
+				done(err)
 				if err != nil {
 					return err
 				}
-				if len(terms) > limit {
-					terms = terms[:limit]
+				if len(terms) > opts.Limit {
+					terms = terms[:opts.Limit]
 				}
 				for i, it := range terms {
 					rank := i + 1
@@ -93,6 +73,12 @@ func newASOHintsCmd() *cobra.Command {
 
 	cmd.Flags().String("countries", "", "Comma-separated country codes (alpha-2), e.g. US,GB")
 	_ = cmd.MarkFlagRequired("countries")
+	addMZSearchHintsFlags(cmd)
+
+	return cmd
+}
+
+func addMZSearchHintsFlags(cmd *cobra.Command) {
 	cmd.Flags().String("query", "", "Prefix query for suggestions")
 	_ = cmd.MarkFlagRequired("query")
 	cmd.Flags().Int("limit", 20, "Max suggestions per country")
@@ -100,8 +86,50 @@ func newASOHintsCmd() *cobra.Command {
 	cmd.Flags().String("client-application", "Software", "clientApplication query param")
 	cmd.Flags().String("media", "software", "media query param")
 	cmd.Flags().Bool("e", true, "e query param")
+}
 
-	return cmd
+type mzHintsOptions struct {
+	Limit      int
+	Storefront string
+	ClientApp  string
+	Media      string
+	E          bool
+}
+
+func getMZSearchHintsOptions(cmd *cobra.Command) mzHintsOptions {
+	limit, _ := cmd.Flags().GetInt("limit")
+	if limit <= 0 {
+		limit = 20
+	}
+
+	storefront, _ := cmd.Flags().GetString("storefront")
+	storefront = strings.TrimSpace(storefront)
+	if storefront == "" {
+		storefront = "143441-1,29 t:native"
+	}
+	recordRunStorefront(storefront)
+
+	clientApp, _ := cmd.Flags().GetString("client-application")
+	clientApp = strings.TrimSpace(clientApp)
+	if clientApp == "" {
+		clientApp = "Software"
+	}
+
+	media, _ := cmd.Flags().GetString("media")
+	media = strings.TrimSpace(media)
+	if media == "" {
+		media = "software"
+	}
+
+	eFlag, _ := cmd.Flags().GetBool("e")
+
+	return mzHintsOptions{
+		Limit:      limit,
+		Storefront: storefront,
+		ClientApp:  clientApp,
+		Media:      media,
+		E:          eFlag,
+	}
 }
 
 type mzHintItem struct {
@@ -8,11 +8,30 @@ import (
 
 var outputFormat string
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 var rootCmd = &cobra.Command{
-	Use:   "aads-aso",
-	Short: "Standalone ASO CLI for unofficial Apple endpoints",
+	Use:     "aads-aso",
+	Version: version,
+	Short:   "Standalone ASO CLI for unofficial Apple endpoints",
 	Long: "Standalone ASO CLI for unofficial Apple endpoints.\n" +
 		"This binary is intentionally separate from aads because these commands rely on undocumented behavior and may break at any time.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Annotations[skipConfigAnnotation] == "" {
+			if _, err := applyConfig(cmd); err != nil {
+				return err
+			}
+		}
+		if err := setupLogger(); err != nil {
+			return err
+		}
+		if err := checkBinaryOutput(outputFormat, stdoutIsTerminal()); err != nil {
+			return err
+		}
+		startRun(cmd)
+		return nil
+	},
 }
 
 func main() {
@@ -22,10 +41,34 @@ func main() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "json", "Output format: json, table, yaml")
+	rootCmd.PersistentFlags().StringVar(&configPathFlag, "config", "", "Config file (default ~/.aads/config.yaml, or $AADS_ASO_CONFIG)")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "", "Named profile from the config file (or $AADS_ASO_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&accountFlag, "account", "", "Apple Ads account: selects its cookie file, browser profile and default adam-id (see accounts)")
+	rootCmd.PersistentFlags().StringVar(&cookieKeyFileFlag, "cookie-key-file", "", "File with the passphrase used to encrypt/decrypt stored cookies (or $AADS_ASO_COOKIE_KEY)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "json", "Output format: json, table, yaml, csv, tsv, ndjson, markdown, html, xlsx, template")
+
+	rootCmd.PersistentFlags().StringVar(&templateFile, "template", "", "Template file (Go text/template) used with --output template")
+	rootCmd.PersistentFlags().StringVar(&columnsFlag, "columns", "", "Comma-separated columns to output, in order (e.g. keyword,country,popularity)")
+	rootCmd.PersistentFlags().StringVar(&sortFlag, "sort", "", "Sort rows by columns, e.g. popularity:desc,keyword (or -popularity,keyword)")
+	rootCmd.PersistentFlags().StringVar(&whereFlag, "where", "", "Filter rows, e.g. 'popularity>=30 && country==US' (ops: == != > >= < <= =~ !~; && binds tighter than ||)")
+	rootCmd.PersistentFlags().BoolVar(&envelopeFlag, "envelope", false, "Wrap json/yaml output in an object with run metadata (adam-id, countries, timings, warnings, version)")
+	rootCmd.PersistentFlags().StringVar(&logFormatFlag, "log-format", "text", "Stderr log format: text or json (one event object per line)")
+	rootCmd.PersistentFlags().StringVar(&logLevelFlag, "log-level", "info", "Stderr log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().BoolVarP(&quietFlag, "quiet", "q", false, "Only log errors (same as --log-level error)")
+	rootCmd.PersistentFlags().StringArrayVar(&sinkFlags, "sink", nil, "Also store all rows in a sink, e.g. sqlite:aso.db (repeatable)")
 
 	rootCmd.AddCommand(newASOPopscoreCmd())
 	rootCmd.AddCommand(newASORecommendCmd())
 	rootCmd.AddCommand(newASOHintsCmd())
+	rootCmd.AddCommand(newASODiscoverCmd())
+	rootCmd.AddCommand(newASOMatrixCmd())
+	rootCmd.AddCommand(newASOClusterCmd())
 	rootCmd.AddCommand(newASOCMCookieCmd())
+	rootCmd.AddCommand(newASOConfigCmd())
+	rootCmd.AddCommand(newASOAccountsCmd())
+	rootCmd.AddCommand(newASOAuthCmd())
+	rootCmd.AddCommand(newASOCampaignsCmd())
+	rootCmd.AddCommand(newASOAppsCmd())
+	rootCmd.AddCommand(newASOResolveCmd())
+	rootCmd.AddCommand(newASOAppCmd())
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultMatrixStrong = 40
+	defaultMatrixWeak   = 5
+)
+
+type asoMatrixRow struct {
+	Keyword    string          `json:"keyword"`
+	Popularity map[string]*int `json:"popularity"`
+	Strong     []string        `json:"strong,omitempty"`
+	Absent     []string        `json:"absent,omitempty"`
+	Gap        bool            `json:"gap"`
+}
+
+// asoMatrix is a keyword x country pivot of popularity. It marshals to its rows for
+// json/yaml and renders one column per country for table output.
+type asoMatrix struct {
+	Countries []string
+	Rows      []asoMatrixRow
+}
+
+func (m asoMatrix) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Rows)
+}
+
+func (m asoMatrix) MarshalYAML() (any, error) {
+	return m.Rows, nil
+}
+
+func (m asoMatrix) tableHeaders() []string {
+	headers := append([]string{"keyword"}, m.Countries...)
+	return append(headers, "gap")
+}
+
+// tableRows leaves unknown popularity empty, so csv, sinks and --where see no value.
+func (m asoMatrix) tableRows() [][]string {
+	return m.cells(func(p *int) string { return formatCell(reflect.ValueOf(p)) })
+}
+
+// tableDisplayRows shows unknown popularity as "-" in table and markdown output.
+func (m asoMatrix) tableDisplayRows() [][]string {
+	return m.cells(matrixCell)
+}
+
+func (m asoMatrix) selectTableRows(idx []int) any {
+	rows := make([]asoMatrixRow, 0, len(idx))
+	for _, i := range idx {
+		rows = append(rows, m.Rows[i])
+	}
+	m.Rows = rows
+	return m
+}
+
+func (m asoMatrix) cells(cell func(*int) string) [][]string {
+	out := make([][]string, 0, len(m.Rows))
+	for _, r := range m.Rows {
+		row := []string{r.Keyword}
+		for _, cc := range m.Countries {
+			row = append(row, cell(r.Popularity[cc]))
+		}
+		row = append(row, matrixGapLabel(r))
+		out = append(out, row)
+	}
+	return out
+}
+
+func matrixCell(p *int) string {
+	if p == nil {
+		return "-"
+	}
+	return strconv.Itoa(*p)
+}
+
+func matrixGapLabel(r asoMatrixRow) string {
+	if !r.Gap {
+		return ""
+	}
+	return "strong: " + strings.Join(r.Strong, ",") + "; absent: " + strings.Join(r.Absent, ",")
+}
+
+func newASOMatrixCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "matrix",
+		Short: "Keyword x country popularity pivot via Apple Ads web endpoint (requires session cookie)",
+		Long: "Fetch keyword popularity for every country and pivot it into one row per keyword and one column per country.\n" +
+			"Rows where a keyword is strong in one market and absent (unknown or below --weak) in another are flagged as gaps.\n" +
+			"Use --output html for a self-contained heatmap.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			countries, err := getCountriesFlag(cmd)
+			if err != nil {
+				return err
+			}
+
+			keywords, err := getKeywordsFlags(cmd)
+			if err != nil {
+				return err
+			}
+			if len(keywords) == 0 {
+				return fmt.Errorf("no keywords provided (use --keywords or --keywords-file)")
+			}
+
+			strong, _ := cmd.Flags().GetInt("strong")
+			weak, _ := cmd.Flags().GetInt("weak")
+			if weak > strong {
+				return fmt.Errorf("--weak (%d) must not be greater than --strong (%d)", weak, strong)
+			}
+
+			session, err := newCMSessionFromFlags(ctx, cmd, countries)
+			if err != nil {
+				return err
+			}
+
+			var rows []asoPopscoreRow
+			for _, cc := range countries {
+				done := trackRunCountry(cc)
+				items, err := session.popularities(ctx, cc, keywords)
+				done(err)
+				if err != nil {
+					return err
+				}
+				rows = append(rows, popscoreRows(keywords, cc, items)...)
+			}
+
+			return printOutput(pivotPopscoreRows(keywords, countries, rows, strong, weak))
+		},
+	}
+
+	addCommonCMKeywordFlags(cmd, true)
+	cmd.Flags().String("keywords", "", "Comma-separated keywords")
+	cmd.Flags().String("keywords-file", "", "Path to file with one keyword per line")
+	cmd.Flags().Int("strong", defaultMatrixStrong, "Popularity at or above which a keyword counts as strong in a country")
+	cmd.Flags().Int("weak", defaultMatrixWeak, "Popularity below which (or when not found) a keyword counts as absent in a country")
+	return cmd
+}
+
+func pivotPopscoreRows(keywords, countries []string, rows []asoPopscoreRow, strong, weak int) asoMatrix {
+	byKeyword := map[string]map[string]*int{}
+	for _, r := range rows {
+		k := normKeyword(r.Keyword)
+		if byKeyword[k] == nil {
+			byKeyword[k] = map[string]*int{}
+		}
+		byKeyword[k][r.Country] = r.Popularity
+	}
+
+	m := asoMatrix{Countries: countries}
+	for _, kw := range keywords {
+		pops := byKeyword[normKeyword(kw)]
+		if pops == nil {
+			pops = map[string]*int{}
+		}
+		row := asoMatrixRow{Keyword: kw, Popularity: map[string]*int{}}
+		for _, cc := range countries {
+			p := pops[cc]
+			row.Popularity[cc] = p
+			switch {
+			case p != nil && *p >= strong:
+				row.Strong = append(row.Strong, cc)
+			case p == nil || *p < weak:
+				row.Absent = append(row.Absent, cc)
+			}
+		}
+		row.Gap = len(row.Strong) > 0 && len(row.Absent) > 0
+		m.Rows = append(m.Rows, row)
+	}
+	return m
+}
+
+// matrixHeatStyle shades a cell from white (0) to green (100); unknown values are grey.
+func matrixHeatStyle(p *int) template.CSS {
+	if p == nil {
+		return template.CSS("background-color: #f5f5f7; color: #86868b")
+	}
+	v := *p
+	if v < 0 {
+		v = 0
+	}
+	if v > 100 {
+		v = 100
+	}
+	return template.CSS(fmt.Sprintf("background-color: hsl(140, 60%%, %d%%)", 97-v*55/100))
+}
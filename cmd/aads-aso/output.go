@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,10 +14,39 @@ import (
 )
 
 func printOutput(data any) error {
+	opts, err := parseRowOptions(columnsFlag, sortFlag, whereFlag)
+	if err != nil {
+		return err
+	}
+	if err := writeSinks(data); err != nil {
+		return err
+	}
+	data, err = applyRowOptions(data, opts)
+	if err != nil {
+		return err
+	}
+
 	switch strings.ToLower(strings.TrimSpace(outputFormat)) {
 	case "table":
 		return printTable(os.Stdout, data)
+	case "csv":
+		return printDelimited(os.Stdout, data, ',')
+	case "tsv":
+		return printDelimited(os.Stdout, data, '\t')
+	case "ndjson":
+		return printNDJSON(os.Stdout, data)
+	case "markdown", "md":
+		return printMarkdown(os.Stdout, data)
+	case "html":
+		return printHTMLReport(os.Stdout, data)
+	case "xlsx":
+		return printXLSX(os.Stdout, data)
+	case "template":
+		return printTemplate(os.Stdout, data, templateFile)
 	case "yaml":
+		if envelopeFlag {
+			data = newRunEnvelope(data)
+		}
 		b, err := yaml.Marshal(data)
 		if err != nil {
 			return err
@@ -24,6 +54,9 @@ func printOutput(data any) error {
 		_, err = os.Stdout.Write(b)
 		return err
 	default:
+		if envelopeFlag {
+			data = newRunEnvelope(data)
+		}
 		b, err := json.MarshalIndent(data, "", "  ")
 		if err != nil {
 			return err
@@ -43,53 +76,204 @@ func printRawJSON(data []byte) error {
 	return printOutput(parsed)
 }
 
+// tableData is implemented by results whose columns are only known at runtime
+// (for example one column per country). printTable renders them as-is instead of
+// reflecting over struct fields.
+type tableData interface {
+	tableHeaders() []string
+	tableRows() [][]string
+}
+
+// tableDisplayData is implemented by tableData results whose human-readable cells
+// differ from the raw ones (for example "-" for an unknown value).
+type tableDisplayData interface {
+	tableDisplayRows() [][]string
+}
+
 func printTable(w io.Writer, data any) error {
-	v := reflect.ValueOf(data)
-	for v.Kind() == reflect.Pointer {
-		if v.IsNil() {
-			return nil
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	ok, err := walkDisplayRows(data, func(cells []string) error {
+		_, err := fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		return err
+	})
+	if ok || err != nil {
+		return err
+	}
+
+	// Fallback: print each element as JSON.
+	v := indirectValue(reflect.ValueOf(data))
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			b, _ := json.Marshal(v.Index(i).Interface())
+			fmt.Fprintln(tw, string(b))
 		}
-		v = v.Elem()
+		return nil
 	}
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+	b, _ := json.Marshal(data)
+	fmt.Fprintln(tw, string(b))
+	return nil
+}
 
-	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
-	defer tw.Flush()
+// printDelimited writes rows as CSV (or TSV with sep '\t'), one record per row as it
+// is visited. Nil pointer fields become empty cells, like in table output.
+func printDelimited(w io.Writer, data any, sep rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+
+	ok, err := walkTableRows(data, cw.Write)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s output needs a list of rows", delimitedFormatName(sep))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func delimitedFormatName(sep rune) string {
+	if sep == '\t' {
+		return "tsv"
+	}
+	return "csv"
+}
+
+// printMarkdown writes a GitHub-flavored Markdown table with the same columns as table output.
+func printMarkdown(w io.Writer, data any) error {
+	first := true
+	ok, err := walkDisplayRows(data, func(cells []string) error {
+		escaped := make([]string, len(cells))
+		for i, c := range cells {
+			escaped[i] = markdownCellEscaper.Replace(c)
+		}
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(escaped, " | ")); err != nil {
+			return err
+		}
+		if first {
+			first = false
+			seps := make([]string, len(cells))
+			for i := range seps {
+				seps[i] = "---"
+			}
+			if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(seps, " | ")); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("markdown output needs a list of rows")
+	}
+	return nil
+}
+
+var markdownCellEscaper = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+// printNDJSON writes one compact JSON document per row.
+func printNDJSON(w io.Writer, data any) error {
+	enc := json.NewEncoder(w)
+	v := indirectValue(reflect.ValueOf(data))
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		for i := 0; i < v.Len(); i++ {
+			if err := enc.Encode(v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Types with custom marshalling (e.g. asoMatrix) still encode to an array.
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		_, err = w.Write(append(b, '\n'))
+		return err
+	}
+	for _, it := range items {
+		if err := enc.Encode(it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkTableRows calls emit with the header row, then with every data row. It handles
+// tableData values, slices of structs and single structs, and reports false for
+// anything else (nothing is emitted in that case). Empty slices emit nothing.
+func walkTableRows(data any, emit func(cells []string) error) (bool, error) {
+	if td, ok := data.(tableData); ok {
+		if err := emit(td.tableHeaders()); err != nil {
+			return true, err
+		}
+		for _, row := range td.tableRows() {
+			if err := emit(row); err != nil {
+				return true, err
+			}
+		}
+		return true, nil
+	}
 
+	v := indirectValue(reflect.ValueOf(data))
 	switch v.Kind() {
 	case reflect.Slice, reflect.Array:
 		if v.Len() == 0 {
-			return nil
+			return true, nil
 		}
 		first := indirectValue(v.Index(0))
-		if first.Kind() == reflect.Struct {
-			headers := structHeaders(first.Type())
-			fmt.Fprintln(tw, strings.Join(headers, "\t"))
-			for i := 0; i < v.Len(); i++ {
-				row := indirectValue(v.Index(i))
-				values := structValues(row, headers)
-				fmt.Fprintln(tw, strings.Join(values, "\t"))
-			}
-			return nil
+		if first.Kind() != reflect.Struct {
+			return false, nil
+		}
+		headers := structHeaders(first.Type())
+		if err := emit(headers); err != nil {
+			return true, err
 		}
-		// Fallback: print each element as JSON.
 		for i := 0; i < v.Len(); i++ {
-			b, _ := json.Marshal(v.Index(i).Interface())
-			fmt.Fprintln(tw, string(b))
+			row := indirectValue(v.Index(i))
+			if err := emit(structValues(row, headers)); err != nil {
+				return true, err
+			}
 		}
-		return nil
+		return true, nil
 	case reflect.Struct:
 		headers := structHeaders(v.Type())
-		values := structValues(v, headers)
-		fmt.Fprintln(tw, strings.Join(headers, "\t"))
-		fmt.Fprintln(tw, strings.Join(values, "\t"))
-		return nil
+		if err := emit(headers); err != nil {
+			return true, err
+		}
+		return true, emit(structValues(v, headers))
 	default:
-		b, _ := json.Marshal(data)
-		fmt.Fprintln(tw, string(b))
-		return nil
+		return false, nil
 	}
 }
 
+// walkDisplayRows is walkTableRows with the display cells of tableDisplayData results.
+func walkDisplayRows(data any, emit func(cells []string) error) (bool, error) {
+	td, ok := data.(tableData)
+	dd, hasDisplay := data.(tableDisplayData)
+	if !ok || !hasDisplay {
+		return walkTableRows(data, emit)
+	}
+	if err := emit(td.tableHeaders()); err != nil {
+		return true, err
+	}
+	for _, row := range dd.tableDisplayRows() {
+		if err := emit(row); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
 func indirectValue(v reflect.Value) reflect.Value {
 	for v.Kind() == reflect.Pointer {
 		if v.IsNil() {
@@ -121,7 +305,22 @@ func structHeaders(t reflect.Type) []string {
 }
 
 func structValues(v reflect.Value, headers []string) []string {
-	t := v.Type()
+	indexByHeader := structFieldIndexes(v.Type())
+
+	out := make([]string, 0, len(headers))
+	for _, h := range headers {
+		i, ok := indexByHeader[h]
+		if !ok {
+			out = append(out, "")
+			continue
+		}
+		out = append(out, formatCell(v.Field(i)))
+	}
+	return out
+}
+
+// structFieldIndexes maps the column names used by structHeaders to field indexes.
+func structFieldIndexes(t reflect.Type) map[string]int {
 	indexByHeader := map[string]int{}
 	for i := 0; i < t.NumField(); i++ {
 		f := t.Field(i)
@@ -138,23 +337,22 @@ func structValues(v reflect.Value, headers []string) []string {
 		}
 		indexByHeader[name] = i
 	}
+	return indexByHeader
+}
 
-	out := make([]string, 0, len(headers))
-	for _, h := range headers {
-		i, ok := indexByHeader[h]
-		if !ok {
-			out = append(out, "")
-			continue
+func formatCell(fv reflect.Value) string {
+	if fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return ""
 		}
-		fv := v.Field(i)
-		if fv.Kind() == reflect.Pointer {
-			if fv.IsNil() {
-				out = append(out, "")
-				continue
-			}
-			fv = fv.Elem()
+		fv = fv.Elem()
+	}
+	if fv.Kind() == reflect.Slice || fv.Kind() == reflect.Array {
+		parts := make([]string, 0, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			parts = append(parts, formatCell(fv.Index(i)))
 		}
-		out = append(out, fmt.Sprint(fv.Interface()))
+		return strings.Join(parts, ", ")
 	}
-	return out
+	return fmt.Sprint(fv.Interface())
 }
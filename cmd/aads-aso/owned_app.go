@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// ownedAppSelector decides which of the account's apps is used when no adam-id was
+// given (or the given one is not owned).
+type ownedAppSelector struct {
+	Query       string // --owned-app: adam-id, bundle ID or app name
+	Country     string // storefront for looking up app names
+	Interactive bool   // may prompt on the terminal
+}
+
+type ownedAppCandidate struct {
+	AdamID    int64
+	Name      string
+	BundleID  string
+	Campaigns []string
+}
+
+func (c ownedAppCandidate) String() string {
+	s := strconv.FormatInt(c.AdamID, 10)
+	if c.Name != "" {
+		s += " " + c.Name
+	}
+	if c.BundleID != "" {
+		s += " (" + c.BundleID + ")"
+	}
+	if len(c.Campaigns) > 0 {
+		s += " - campaigns: " + strings.Join(c.Campaigns, ", ")
+	}
+	return s
+}
+
+func ownedAppSelectorFromFlags(cmd *cobra.Command, countries []string) ownedAppSelector {
+	query, _ := cmd.Flags().GetString("owned-app")
+	return ownedAppSelector{
+		Query:       strings.TrimSpace(query),
+		Country:     adamLookupCountry(cmd, countries),
+		Interactive: stdinIsTerminal(),
+	}
+}
+
+// ownedAppCandidates groups campaigns by adam-id, in campaign order.
+func ownedAppCandidates(campaigns []cmCampaignItem) []ownedAppCandidate {
+	var out []ownedAppCandidate
+	index := map[int64]int{}
+	for _, c := range campaigns {
+		if c.AdamID <= 0 {
+			continue
+		}
+		i, seen := index[c.AdamID]
+		if !seen {
+			i = len(out)
+			index[c.AdamID] = i
+			out = append(out, ownedAppCandidate{AdamID: c.AdamID})
+		}
+		if name := strings.TrimSpace(c.Name); name != "" {
+			out[i].Campaigns = append(out[i].Campaigns, name)
+		}
+	}
+	return out
+}
+
+// enrichOwnedAppCandidates adds App Store names and bundle IDs. When the lookup fails
+// the candidates are left unchanged and are still usable by adam-id.
+func enrichOwnedAppCandidates(ctx context.Context, candidates []ownedAppCandidate, country string) error {
+	ids := make([]int64, 0, len(candidates))
+	for _, c := range candidates {
+		ids = append(ids, c.AdamID)
+	}
+	found, err := lookupAppsByID(ctx, ids, country)
+	if err != nil {
+		return fmt.Errorf("look up owned apps in the App Store: %w", err)
+	}
+	for i := range candidates {
+		if it, ok := found[candidates[i].AdamID]; ok {
+			candidates[i].Name = strings.TrimSpace(it.TrackName)
+			candidates[i].BundleID = strings.TrimSpace(it.BundleID)
+		}
+	}
+	return nil
+}
+
+// selectOwnedApp picks one candidate: the only one, the one matching --owned-app, or
+// the user's choice on a terminal. It never picks arbitrarily among several apps.
+func selectOwnedApp(ctx context.Context, candidates []ownedAppCandidate, sel ownedAppSelector) (ownedAppCandidate, error) {
+	if len(candidates) == 0 {
+		return ownedAppCandidate{}, fmt.Errorf("no owned adam-id found in Apple Ads campaigns")
+	}
+	if sel.Query == "" && len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	if id, err := strconv.ParseInt(sel.Query, 10, 64); err == nil && id > 0 {
+		for _, c := range candidates {
+			if c.AdamID == id {
+				return c, nil
+			}
+		}
+		return ownedAppCandidate{}, fmt.Errorf("--owned-app %d is not used by any campaign of this account; owned apps:\n%s", id, formatOwnedAppCandidates(candidates))
+	}
+
+	lookupErr := enrichOwnedAppCandidates(ctx, candidates, sel.Country)
+	if lookupErr != nil {
+		warnRun("app_lookup_failed", lookupErr.Error()+"; owned apps are listed by adam-id only", "country", sel.Country)
+	}
+	if sel.Query != "" {
+		matches := matchOwnedApps(candidates, sel.Query)
+		switch len(matches) {
+		case 1:
+			return matches[0], nil
+		case 0:
+			if lookupErr != nil {
+				return ownedAppCandidate{}, fmt.Errorf("--owned-app %q cannot be matched by name or bundle ID (%v); use an adam-id:\n%s", sel.Query, lookupErr, formatOwnedAppCandidates(candidates))
+			}
+			return ownedAppCandidate{}, fmt.Errorf("--owned-app %q matches none of the account's apps; owned apps:\n%s", sel.Query, formatOwnedAppCandidates(candidates))
+		}
+		candidates = matches
+		if !sel.Interactive {
+			return ownedAppCandidate{}, fmt.Errorf("--owned-app %q matches several apps; use a bundle ID or adam-id:\n%s", sel.Query, formatOwnedAppCandidates(matches))
+		}
+	}
+
+	if !sel.Interactive {
+		return ownedAppCandidate{}, fmt.Errorf("the account has %d apps; choose one with --owned-app (adam-id, bundle ID or name) or --adam-id:\n%s", len(candidates), formatOwnedAppCandidates(candidates))
+	}
+	return pickOwnedApp(candidates)
+}
+
+// matchOwnedApps prefers exact bundle ID or name matches, then name substrings.
+func matchOwnedApps(candidates []ownedAppCandidate, query string) []ownedAppCandidate {
+	q := strings.ToLower(strings.TrimSpace(query))
+	var exact, partial []ownedAppCandidate
+	for _, c := range candidates {
+		name, bundle := strings.ToLower(c.Name), strings.ToLower(c.BundleID)
+		switch {
+		case q == bundle || q == name:
+			exact = append(exact, c)
+		case name != "" && strings.Contains(name, q):
+			partial = append(partial, c)
+		}
+	}
+	if len(exact) > 0 {
+		return exact
+	}
+	return partial
+}
+
+func formatOwnedAppCandidates(candidates []ownedAppCandidate) string {
+	lines := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		lines = append(lines, "  "+c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// pickOwnedApp asks on the terminal.
+func pickOwnedApp(candidates []ownedAppCandidate) (ownedAppCandidate, error) {
+	items := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		items = append(items, c.String())
+	}
+	i, ok := pickFromTerminal("This Apple Ads account has several apps:", "Choose an app", items)
+	if !ok {
+		return ownedAppCandidate{}, fmt.Errorf("no app chosen; pass --owned-app or --adam-id")
+	}
+	return candidates[i], nil
+}
+
+// pickFromTerminal shows a numbered list and reads the choice. The list and prompt go
+// to stderr so they show even with --quiet and never mix into the output. It returns
+// false when stdin ends without a valid choice.
+func pickFromTerminal(header, prompt string, items []string) (int, bool) {
+	fmt.Fprintln(os.Stderr, header)
+	for i, it := range items {
+		fmt.Fprintf(os.Stderr, "  %d) %s\n", i+1, it)
+	}
+	in := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprintf(os.Stderr, "%s [1-%d]: ", prompt, len(items))
+		line, err := in.ReadString('\n')
+		if n, convErr := strconv.Atoi(strings.TrimSpace(line)); convErr == nil && n >= 1 && n <= len(items) {
+			return n - 1, true
+		}
+		if err != nil {
+			return 0, false
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// htmlReport is the view model of --output html: a single offline page with run
+// metadata, an optional keyword x country heatmap and one sortable table per country.
+type htmlReport struct {
+	Title       string
+	GeneratedAt string
+	Run         runInfo
+	RowCount    int
+	Heatmap     *asoMatrix
+	Sections    []htmlReportSection
+}
+
+type htmlReportSection struct {
+	Title   string
+	Headers []string
+	Rows    [][]htmlReportCell
+}
+
+type htmlReportCell struct {
+	Text string
+	// Bar is the popularity bar length (0-100), or -1 when the cell has no bar.
+	Bar int
+}
+
+func printHTMLReport(w io.Writer, data any) error {
+	report := htmlReport{
+		Title:       "Keyword report",
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Run:         currentRun,
+	}
+	if report.Run.Command != "" {
+		report.Title = "Keyword report: " + report.Run.Command
+	}
+
+	var (
+		headers []string
+		rows    [][]string
+	)
+	ok, err := walkTableRows(data, func(cells []string) error {
+		if headers == nil {
+			headers = cells
+			return nil
+		}
+		rows = append(rows, cells)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("html output needs a list of rows")
+	}
+	report.RowCount = len(rows)
+
+	if m, isMatrix := data.(asoMatrix); isMatrix {
+		report.Heatmap = &m
+	} else if m, derived := pivotTableCells(headers, rows); derived {
+		report.Heatmap = &m
+	}
+
+	col := map[string]int{}
+	for i, h := range headers {
+		col[strings.ToLower(h)] = i
+	}
+	popCol, hasPop := col["popularity"]
+	countryCol, hasCountry := col["country"]
+
+	toCells := func(r []string) []htmlReportCell {
+		out := make([]htmlReportCell, len(r))
+		for i, c := range r {
+			out[i] = htmlReportCell{Text: c, Bar: -1}
+			if hasPop && i == popCol {
+				if n, err := strconv.Atoi(c); err == nil {
+					out[i].Bar = min(max(n, 0), 100)
+				}
+			}
+		}
+		return out
+	}
+
+	if !hasCountry {
+		section := htmlReportSection{Title: "Results", Headers: headers}
+		for _, r := range rows {
+			section.Rows = append(section.Rows, toCells(r))
+		}
+		report.Sections = append(report.Sections, section)
+	} else {
+		index := map[string]int{}
+		for _, r := range rows {
+			cc := r[countryCol]
+			i, seen := index[cc]
+			if !seen {
+				i = len(report.Sections)
+				index[cc] = i
+				title := cc
+				if title == "" {
+					title = "(no country)"
+				}
+				report.Sections = append(report.Sections, htmlReportSection{Title: title, Headers: headers})
+			}
+			report.Sections[i].Rows = append(report.Sections[i].Rows, toCells(r))
+		}
+	}
+
+	return htmlReportTemplate.Execute(w, report)
+}
+
+// pivotTableCells builds a heatmap from long-format rows that have a keyword (or term),
+// a country and a popularity column. It reports false for any other shape.
+func pivotTableCells(headers []string, rows [][]string) (asoMatrix, bool) {
+	col := map[string]int{}
+	for i, h := range headers {
+		col[strings.ToLower(h)] = i
+	}
+	kwCol, ok := col["keyword"]
+	if !ok {
+		if kwCol, ok = col["term"]; !ok {
+			return asoMatrix{}, false
+		}
+	}
+	countryCol, okCountry := col["country"]
+	popCol, okPop := col["popularity"]
+	if !okCountry || !okPop || len(rows) == 0 {
+		return asoMatrix{}, false
+	}
+
+	var keywords, countries []string
+	seenCountry := map[string]bool{}
+	var popRows []asoPopscoreRow
+	for _, r := range rows {
+		cc := r[countryCol]
+		if !seenCountry[cc] {
+			seenCountry[cc] = true
+			countries = append(countries, cc)
+		}
+		row := asoPopscoreRow{Keyword: r[kwCol], Country: cc}
+		if n, err := strconv.Atoi(r[popCol]); err == nil {
+			row.Popularity = &n
+		}
+		keywords = append(keywords, r[kwCol])
+		popRows = append(popRows, row)
+	}
+	return pivotPopscoreRows(dedupeKeywords(keywords), countries, popRows, defaultMatrixStrong, defaultMatrixWeak), true
+}
+
+func dedupeKeywords(keywords []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, kw := range keywords {
+		n := normKeyword(kw)
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, kw)
+	}
+	return out
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"cell":     matrixCell,
+	"heat":     matrixHeatStyle,
+	"gapLabel": matrixGapLabel,
+	"isStrong": func(r asoMatrixRow, cc string) bool { return slices.Contains(r.Strong, cc) },
+	"isAbsent": func(r asoMatrixRow, cc string) bool { return r.Gap && slices.Contains(r.Absent, cc) },
+	"rfc3339":  func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 24px; color: #1d1d1f; }
+h2 { margin-top: 32px; }
+table { border-collapse: collapse; margin-bottom: 16px; }
+th, td { border: 1px solid #d2d2d7; padding: 4px 10px; text-align: left; vertical-align: top; }
+table.sortable th { cursor: pointer; user-select: none; background: #f5f5f7; }
+table.sortable th.asc::after { content: " \25B2"; }
+table.sortable th.desc::after { content: " \25BC"; }
+table.meta th { background: #f5f5f7; }
+table.heatmap td { text-align: center; }
+table.heatmap td:first-child { text-align: left; }
+td.strong { font-weight: 600; }
+td.absent { outline: 2px solid #d70015; outline-offset: -2px; }
+td.gap { color: #d70015; font-size: 12px; text-align: left; }
+.bar { display: flex; align-items: center; gap: 6px; }
+.bar span { display: inline-block; height: 10px; background: #34c759; border-radius: 2px; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+
+<h2>Run</h2>
+<table class="meta">
+<tr><th>Generated</th><td>{{.GeneratedAt}}</td></tr>
+{{- if .Run.Command}}
+<tr><th>Command</th><td>{{.Run.Command}}</td></tr>
+<tr><th>Started</th><td>{{rfc3339 .Run.StartedAt}}</td></tr>
+{{- end}}
+{{- if .Run.AdamID}}
+<tr><th>adam-id</th><td>{{.Run.AdamID}}{{with .Run.AdamIDSource}} (from {{.}}){{end}}</td></tr>
+{{- end}}
+{{- if .Run.AppName}}
+<tr><th>App</th><td>{{.Run.AppName}}</td></tr>
+{{- end}}
+<tr><th>Rows</th><td>{{.RowCount}}</td></tr>
+{{- range .Run.Warnings}}
+<tr><th>Warning</th><td>{{.}}</td></tr>
+{{- end}}
+{{- range .Run.Flags}}
+<tr><th>--{{.Name}}</th><td>{{.Value}}{{if .Source}} ({{.Source}}){{end}}</td></tr>
+{{- end}}
+</table>
+
+{{- with .Heatmap}}
+<h2>Popularity by country</h2>
+<table class="heatmap">
+<thead><tr><th>keyword</th>{{range .Countries}}<th>{{.}}</th>{{end}}<th>gap</th></tr></thead>
+<tbody>
+{{- range $r := .Rows}}
+<tr><td>{{$r.Keyword}}</td>{{range $cc := $.Heatmap.Countries}}{{$p := index $r.Popularity $cc}}<td style="{{heat $p}}"{{if isStrong $r $cc}} class="strong"{{else if isAbsent $r $cc}} class="absent"{{end}}>{{cell $p}}</td>{{end}}<td class="gap">{{gapLabel $r}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+
+{{- range .Sections}}
+<h2>{{.Title}}</h2>
+<table class="sortable">
+<thead><tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr></thead>
+<tbody>
+{{- range .Rows}}
+<tr>{{range .}}<td data-sort="{{.Text}}">{{if ge .Bar 0}}<div class="bar"><span style="width: {{.Bar}}px"></span>{{.Text}}</div>{{else}}{{.Text}}{{end}}</td>{{end}}</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+
+<script>
+document.querySelectorAll("table.sortable").forEach(function (table) {
+  table.querySelectorAll("th").forEach(function (th, col) {
+    th.addEventListener("click", function () {
+      var asc = !th.classList.contains("asc");
+      table.querySelectorAll("th").forEach(function (h) { h.classList.remove("asc", "desc"); });
+      th.classList.add(asc ? "asc" : "desc");
+      var body = table.tBodies[0];
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = a.cells[col].dataset.sort, y = b.cells[col].dataset.sort;
+        if (x === "" || y === "") { return x === y ? 0 : (x === "" ? 1 : -1); }
+        var nx = Number(x), ny = Number(y);
+        var c = (!isNaN(nx) && !isNaN(ny)) ? nx - ny : x.localeCompare(y);
+        return asc ? c : -c;
+      });
+      rows.forEach(function (r) { body.appendChild(r); });
+    });
+  });
+});
+</script>
+</body>
+</html>
+`))
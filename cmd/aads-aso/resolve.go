@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	appNameSearchLimit          = 10
+	defaultMinAppNameConfidence = 0.85
+)
+
+// appNameCandidate is an App Store search result scored against the searched name.
+type appNameCandidate struct {
+	AdamID    int64
+	Name      string
+	BundleID  string
+	Developer string
+	Score     float64 // 1 for an exact title match; see appNameSimilarity
+}
+
+func (c appNameCandidate) String() string {
+	s := fmt.Sprintf("%d %s", c.AdamID, c.Name)
+	if c.Developer != "" {
+		s += " by " + c.Developer
+	}
+	if c.BundleID != "" {
+		s += " (" + c.BundleID + ")"
+	}
+	return s + fmt.Sprintf(" - score %.2f", c.Score)
+}
+
+type asoResolveRow struct {
+	Input     string  `json:"input"`
+	Source    string  `json:"source"` // app-url, bundle-id or app-name
+	Country   string  `json:"country"`
+	Rank      int     `json:"rank"`
+	AdamID    int64   `json:"adamId"`
+	Name      string  `json:"name,omitempty"`
+	BundleID  string  `json:"bundleId,omitempty"`
+	Developer string  `json:"developer,omitempty"`
+	Score     float64 `json:"score"`
+	Selected  bool    `json:"selected"`
+}
+
+// rankAppNameCandidates scores search results against query, best first. Equal scores
+// keep the App Store's order.
+func rankAppNameCandidates(query string, results []itunesAppEntry) []appNameCandidate {
+	out := make([]appNameCandidate, 0, len(results))
+	for _, it := range results {
+		if it.TrackID <= 0 {
+			continue
+		}
+		name := strings.TrimSpace(it.TrackName)
+		out = append(out, appNameCandidate{
+			AdamID:    it.TrackID,
+			Name:      name,
+			BundleID:  strings.TrimSpace(it.BundleID),
+			Developer: strings.TrimSpace(it.ArtistName),
+			Score:     appNameSimilarity(query, name),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// appNameSimilarity compares case- and accent-folded words: 1 for the same title, 0.95
+// when the query is the title before its subtitle ("Name: Subtitle", "Name - ..."),
+// otherwise the better of word Jaccard and trigram Dice (as in cluster), scaled so a
+// fuzzy match never outranks an exact one.
+func appNameSimilarity(query, title string) float64 {
+	qTokens := keywordTokens(query)
+	q := strings.Join(qTokens, " ")
+	t := strings.Join(keywordTokens(title), " ")
+	if q == "" || t == "" {
+		return 0
+	}
+	if q == t {
+		return 1
+	}
+	main := strings.Join(keywordTokens(appTitleMain(title)), " ")
+	if main == q {
+		return 0.95
+	}
+	best := 0.0
+	for _, s := range []string{t, main} {
+		if s == "" {
+			continue
+		}
+		sim := math.Max(jaccard(qTokens, strings.Fields(s)), dice(charTrigrams(q), charTrigrams(s)))
+		best = math.Max(best, sim)
+	}
+	return math.Round(0.9*best*1000) / 1000
+}
+
+// appTitleMain strips an App Store title's subtitle part.
+func appTitleMain(title string) string {
+	cut := len(title)
+	for _, sep := range []string{":", " - ", " – ", " — ", "|"} {
+		if i := strings.Index(title, sep); i > 0 && i < cut {
+			cut = i
+		}
+	}
+	return title[:cut]
+}
+
+// appNameAmbiguity explains why the top candidate cannot be taken as is, or returns "".
+func appNameAmbiguity(candidates []appNameCandidate, minConfidence float64) string {
+	if len(candidates) == 0 {
+		return "no candidates"
+	}
+	top := candidates[0]
+	if top.Score < minConfidence {
+		return fmt.Sprintf("best match %q scores %.2f, below --min-confidence %.2f", top.Name, top.Score, minConfidence)
+	}
+	if len(candidates) > 1 && candidates[1].Score >= top.Score {
+		return fmt.Sprintf("%q and %q match equally well (score %.2f)", top.Name, candidates[1].Name, top.Score)
+	}
+	return ""
+}
+
+// selectAppNameCandidate takes the top candidate when it is unambiguous. Otherwise it
+// asks on a terminal, or fails with the ranked candidates.
+func selectAppNameCandidate(query string, candidates []appNameCandidate, minConfidence float64, interactive bool) (appNameCandidate, error) {
+	reason := appNameAmbiguity(candidates, minConfidence)
+	if reason == "" {
+		return candidates[0], nil
+	}
+	if len(candidates) == 0 {
+		return appNameCandidate{}, fmt.Errorf("no App Store result for app-name %q", query)
+	}
+	if !interactive {
+		return appNameCandidate{}, fmt.Errorf("app-name %q is ambiguous: %s; pass --adam-id, --bundle-id or --app-url instead. Candidates:\n%s",
+			query, reason, formatAppNameCandidates(candidates))
+	}
+	items := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		items = append(items, c.String())
+	}
+	i, ok := pickFromTerminal(fmt.Sprintf("App name %q is ambiguous (%s):", query, reason), "Choose an app", items)
+	if !ok {
+		return appNameCandidate{}, fmt.Errorf("no app chosen for app-name %q; pass --adam-id, --bundle-id or --app-url", query)
+	}
+	return candidates[i], nil
+}
+
+func formatAppNameCandidates(candidates []appNameCandidate) string {
+	lines := make([]string, 0, len(candidates))
+	for i, c := range candidates {
+		lines = append(lines, fmt.Sprintf("  %d) %s", i+1, c))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newASOResolveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resolve",
+		Short: "Show how --app-url, --bundle-id and --app-name inputs resolve to an adam-id",
+		Long: "Resolve App Store inputs the way popscore/recommend/discover do and print the result.\n" +
+			"For --app-name, all ranked candidates are listed with their score; the row that would be\n" +
+			"used has selected=true. When the match is ambiguous, no row is selected and a warning says why.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			appURLs, _ := cmd.Flags().GetStringArray("app-url")
+			bundleIDs, _ := cmd.Flags().GetStringArray("bundle-id")
+			appNames, _ := cmd.Flags().GetStringArray("app-name")
+			if len(appURLs)+len(bundleIDs)+len(appNames) == 0 {
+				return fmt.Errorf("provide at least one of --app-url, --bundle-id or --app-name")
+			}
+			country, _ := cmd.Flags().GetString("country")
+			country = strings.ToUpper(strings.TrimSpace(country))
+			if country == "" {
+				country = defaultAdamCountry
+			}
+			minConfidence, _ := cmd.Flags().GetFloat64("min-confidence")
+			limit, _ := cmd.Flags().GetInt("limit")
+			if limit <= 0 {
+				limit = appNameSearchLimit
+			}
+
+			var out []asoResolveRow
+			for _, raw := range appURLs {
+				id, err := parseAdamIDFromAppURL(raw)
+				if err != nil {
+					return fmt.Errorf("parse --app-url: %w", err)
+				}
+				row := asoResolveRow{Input: raw, Source: adamIDSourceAppURL, Country: country, Rank: 1, AdamID: id, Score: 1, Selected: true}
+				fillResolveRow(ctx, &row)
+				out = append(out, row)
+			}
+			for _, raw := range bundleIDs {
+				bundleID := strings.TrimSpace(raw)
+				id, _, err := lookupAdamIDByBundleID(ctx, bundleID, country)
+				if err != nil {
+					return fmt.Errorf("resolve --bundle-id %q: %w", bundleID, err)
+				}
+				row := asoResolveRow{Input: bundleID, Source: adamIDSourceBundleID, Country: country, Rank: 1, AdamID: id, Score: 1, Selected: true}
+				fillResolveRow(ctx, &row)
+				out = append(out, row)
+			}
+			for _, raw := range appNames {
+				appName := strings.TrimSpace(raw)
+				candidates, err := searchAppNameCandidates(ctx, appName, country, limit)
+				if err != nil {
+					return fmt.Errorf("resolve --app-name %q: %w", appName, err)
+				}
+				reason := appNameAmbiguity(candidates, minConfidence)
+				if reason != "" {
+					warnRun("app_name_ambiguous", fmt.Sprintf("App name %q is ambiguous: %s", appName, reason), "query", appName, "country", country)
+				}
+				for i, c := range candidates {
+					out = append(out, asoResolveRow{
+						Input:     appName,
+						Source:    adamIDSourceAppName,
+						Country:   country,
+						Rank:      i + 1,
+						AdamID:    c.AdamID,
+						Name:      c.Name,
+						BundleID:  c.BundleID,
+						Developer: c.Developer,
+						Score:     c.Score,
+						Selected:  i == 0 && reason == "",
+					})
+				}
+			}
+			return printOutput(out)
+		},
+	}
+
+	cmd.Flags().StringArray("app-url", nil, "App Store URL or numeric id to resolve (repeatable)")
+	cmd.Flags().StringArray("bundle-id", nil, "Bundle ID to resolve via iTunes Lookup (repeatable)")
+	cmd.Flags().StringArray("app-name", nil, "App name to resolve via iTunes Search (repeatable)")
+	cmd.Flags().String("country", defaultAdamCountry, "Storefront used for lookup and search")
+	addMinConfidenceFlag(cmd)
+	cmd.Flags().Int("limit", appNameSearchLimit, "Number of search results to rank for --app-name")
+	return cmd
+}
+
+func addMinConfidenceFlag(cmd *cobra.Command) {
+	cmd.Flags().Float64("min-confidence", defaultMinAppNameConfidence,
+		"Lowest score (0-1) at which --app-name takes the best search match without asking; 1 requires an exact title")
+}
+
+// fillResolveRow adds name, bundle ID and developer from iTunes Lookup. A failed
+// lookup leaves them empty and is reported as a warning.
+func fillResolveRow(ctx context.Context, row *asoResolveRow) {
+	found, err := lookupAppsByID(ctx, []int64{row.AdamID}, row.Country)
+	if err != nil {
+		warnRun("app_lookup_failed", fmt.Sprintf("Could not look up adam-id %d in the App Store: %v", row.AdamID, err), "adam_id", row.AdamID)
+		return
+	}
+	it, ok := found[row.AdamID]
+	if !ok {
+		logDebug("app_lookup_failed", "adam-id "+strconv.FormatInt(row.AdamID, 10)+" not found in "+row.Country, "adam_id", row.AdamID)
+		return
+	}
+	row.Name = strings.TrimSpace(it.TrackName)
+	row.BundleID = strings.TrimSpace(it.BundleID)
+	row.Developer = strings.TrimSpace(it.ArtistName)
+}
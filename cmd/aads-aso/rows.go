@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	columnsFlag string
+	sortFlag    string
+	whereFlag   string
+)
+
+type rowSortKey struct {
+	column string
+	desc   bool
+}
+
+type rowCondition struct {
+	column string
+	op     string
+	value  string
+}
+
+// rowFilter is a disjunction of conjunctions: a row matches when every condition of
+// at least one group matches ("a && b || c" is [[a b] [c]]).
+type rowFilter [][]rowCondition
+
+type rowOptions struct {
+	columns []string
+	sort    []rowSortKey
+	where   rowFilter
+}
+
+func (o rowOptions) empty() bool {
+	return len(o.columns) == 0 && len(o.sort) == 0 && len(o.where) == 0
+}
+
+var rowConditionPattern = regexp.MustCompile(`^\s*([A-Za-z0-9_.-]+)\s*(==|!=|>=|<=|=~|!~|=|>|<)\s*(.*?)\s*$`)
+
+func parseRowOptions(columns, sortSpec, where string) (rowOptions, error) {
+	var opts rowOptions
+	for _, c := range strings.Split(columns, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			opts.columns = append(opts.columns, c)
+		}
+	}
+
+	for _, k := range strings.Split(sortSpec, ",") {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			continue
+		}
+		key := rowSortKey{column: k}
+		switch {
+		case strings.HasPrefix(k, "-"):
+			key = rowSortKey{column: strings.TrimSpace(k[1:]), desc: true}
+		case strings.HasPrefix(k, "+"):
+			key = rowSortKey{column: strings.TrimSpace(k[1:])}
+		case strings.Contains(k, ":"):
+			i := strings.LastIndexByte(k, ':')
+			dir := strings.ToLower(strings.TrimSpace(k[i+1:]))
+			if dir != "asc" && dir != "desc" {
+				return rowOptions{}, fmt.Errorf("invalid --sort key %q (direction must be asc or desc)", k)
+			}
+			key = rowSortKey{column: strings.TrimSpace(k[:i]), desc: dir == "desc"}
+		}
+		if key.column == "" {
+			return rowOptions{}, fmt.Errorf("invalid --sort key %q", k)
+		}
+		opts.sort = append(opts.sort, key)
+	}
+
+	if strings.TrimSpace(where) != "" {
+		for _, group := range splitOutsideQuotes(where, "||") {
+			var conds []rowCondition
+			for _, part := range splitOutsideQuotes(group, "&&") {
+				m := rowConditionPattern.FindStringSubmatch(part)
+				if m == nil {
+					return rowOptions{}, fmt.Errorf("invalid --where condition %q (expected e.g. popularity>=30 or country==US)", strings.TrimSpace(part))
+				}
+				op := m[2]
+				if op == "=" {
+					op = "=="
+				}
+				conds = append(conds, rowCondition{column: m[1], op: op, value: unquoteRowValue(m[3])})
+			}
+			opts.where = append(opts.where, conds)
+		}
+	}
+	return opts, nil
+}
+
+// splitOutsideQuotes splits s at sep, except inside '...' or "..." values.
+func splitOutsideQuotes(s, sep string) []string {
+	var out []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case strings.HasPrefix(s[i:], sep):
+			out = append(out, s[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+func unquoteRowValue(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// tableRowSelector is implemented by tableData results that keep their own type when
+// --where and --sort pick and reorder rows; idx refers to tableRows().
+type tableRowSelector interface {
+	selectTableRows(idx []int) any
+}
+
+// applyRowOptions filters, sorts and projects the rows passed to printOutput according
+// to --where, --sort and --columns. Without --columns the original row type is kept
+// (for tableData only if it is a tableRowSelector), so every output format behaves as
+// before; with --columns rows become projectedRows.
+func applyRowOptions(data any, opts rowOptions) (any, error) {
+	if opts.empty() {
+		return data, nil
+	}
+
+	headers, records, ok := collectRowRecords(data)
+	if !ok {
+		return nil, fmt.Errorf("--columns, --sort and --where need a list of rows")
+	}
+	if len(headers) == 0 {
+		return data, nil
+	}
+
+	lookup := map[string]int{}
+	for i, h := range headers {
+		lookup[strings.ToLower(h)] = i
+	}
+	column := func(name string) (int, error) {
+		i, ok := lookup[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("unknown column %q (available: %s)", name, strings.Join(headers, ", "))
+		}
+		return i, nil
+	}
+
+	if len(opts.where) > 0 {
+		var kept []rowRecord
+		for _, rec := range records {
+			ok, err := rowFilterMatches(opts.where, rec, column)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				kept = append(kept, rec)
+			}
+		}
+		records = kept
+	}
+
+	if len(opts.sort) > 0 {
+		idx := make([]int, len(opts.sort))
+		for i, k := range opts.sort {
+			c, err := column(k.column)
+			if err != nil {
+				return nil, err
+			}
+			idx[i] = c
+		}
+		sort.SliceStable(records, func(a, b int) bool {
+			for i, k := range opts.sort {
+				c := compareRowCells(records[a], records[b], idx[i], k.desc)
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+	}
+
+	if len(opts.columns) == 0 {
+		if sel, ok := data.(tableRowSelector); ok {
+			idx := make([]int, 0, len(records))
+			for _, rec := range records {
+				idx = append(idx, int(rec.source.Int()))
+			}
+			return sel.selectTableRows(idx), nil
+		}
+		if _, ok := data.(tableData); ok {
+			return projectRows(headers, records, nil), nil
+		}
+		v := indirectValue(reflect.ValueOf(data))
+		out := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), 0, len(records))
+		for _, rec := range records {
+			out = reflect.Append(out, rec.source)
+		}
+		return out.Interface(), nil
+	}
+
+	cols := make([]int, 0, len(opts.columns))
+	for _, name := range opts.columns {
+		c, err := column(name)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return projectRows(headers, records, cols), nil
+}
+
+// collectRowRecords prepares the rows of a tableData value or of a slice of structs.
+// It reports false for any other shape.
+func collectRowRecords(data any) ([]string, []rowRecord, bool) {
+	if td, ok := data.(tableData); ok {
+		var records []rowRecord
+		for i, cells := range td.tableRows() {
+			rec := rowRecord{source: reflect.ValueOf(i), cells: cells, values: make([]any, len(cells))}
+			for i, c := range cells {
+				rec.values[i] = tableCellValue(c)
+				rec.null = append(rec.null, c == "")
+			}
+			records = append(records, rec)
+		}
+		return td.tableHeaders(), records, true
+	}
+
+	v := indirectValue(reflect.ValueOf(data))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, nil, false
+	}
+	if v.Len() == 0 {
+		return nil, nil, true
+	}
+	first := indirectValue(v.Index(0))
+	if first.Kind() != reflect.Struct {
+		return nil, nil, false
+	}
+	headers := structHeaders(first.Type())
+	records := make([]rowRecord, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		records = append(records, structRowRecord(v.Index(i), headers))
+	}
+	return headers, records, true
+}
+
+// rowRecord is one row prepared for filtering and sorting: its source (the slice
+// element, or the row index for tableData), display cells (as in table output), raw
+// values for structured output, and which cells are null.
+type rowRecord struct {
+	source reflect.Value
+	cells  []string
+	values []any
+	null   []bool
+}
+
+func structRowRecord(elem reflect.Value, headers []string) rowRecord {
+	row := indirectValue(elem)
+	index := structFieldIndexes(row.Type())
+	rec := rowRecord{source: elem, cells: structValues(row, headers)}
+	for _, h := range headers {
+		fv := row.Field(index[h])
+		isNil := fv.Kind() == reflect.Pointer && fv.IsNil()
+		rec.null = append(rec.null, isNil)
+		switch {
+		case isNil:
+			rec.values = append(rec.values, nil)
+		case fv.Kind() == reflect.Pointer:
+			rec.values = append(rec.values, fv.Elem().Interface())
+		default:
+			rec.values = append(rec.values, fv.Interface())
+		}
+	}
+	return rec
+}
+
+// tableCellValue turns a rendered cell back into a typed value for structured output.
+func tableCellValue(c string) any {
+	if c == "" {
+		return nil
+	}
+	if n, err := strconv.ParseInt(c, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(c, 64); err == nil {
+		return f
+	}
+	return c
+}
+
+func rowFilterMatches(f rowFilter, rec rowRecord, column func(string) (int, error)) (bool, error) {
+	for _, group := range f {
+		all := true
+		for _, cond := range group {
+			c, err := column(cond.column)
+			if err != nil {
+				return false, err
+			}
+			if !rowConditionMatches(cond, rec.cells[c], rec.null[c]) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func rowConditionMatches(cond rowCondition, cell string, null bool) bool {
+	switch cond.op {
+	case "=~":
+		return strings.Contains(strings.ToLower(cell), strings.ToLower(cond.value))
+	case "!~":
+		return !strings.Contains(strings.ToLower(cell), strings.ToLower(cond.value))
+	}
+
+	a, aErr := strconv.ParseFloat(cell, 64)
+	b, bErr := strconv.ParseFloat(cond.value, 64)
+	numeric := !null && aErr == nil && bErr == nil
+
+	switch cond.op {
+	case "==":
+		if numeric {
+			return a == b
+		}
+		return strings.EqualFold(cell, cond.value)
+	case "!=":
+		if numeric {
+			return a != b
+		}
+		return !strings.EqualFold(cell, cond.value)
+	}
+
+	// Ordering comparisons never match missing values.
+	if null {
+		return false
+	}
+	var c int
+	if numeric {
+		c = compareFloats(a, b)
+	} else {
+		c = strings.Compare(strings.ToLower(cell), strings.ToLower(cond.value))
+	}
+	switch cond.op {
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	}
+	return false
+}
+
+// compareRowCells orders two rows by one column. Missing values sort last in both
+// directions; numbers compare numerically, everything else case-insensitively.
+func compareRowCells(a, b rowRecord, col int, desc bool) int {
+	aNull := a.null[col] || a.cells[col] == ""
+	bNull := b.null[col] || b.cells[col] == ""
+	switch {
+	case aNull && bNull:
+		return 0
+	case aNull:
+		return 1
+	case bNull:
+		return -1
+	}
+
+	var c int
+	af, aErr := strconv.ParseFloat(a.cells[col], 64)
+	bf, bErr := strconv.ParseFloat(b.cells[col], 64)
+	if aErr == nil && bErr == nil {
+		c = compareFloats(af, bf)
+	} else {
+		c = strings.Compare(strings.ToLower(a.cells[col]), strings.ToLower(b.cells[col]))
+	}
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// projectedRows is a column selection of rows. It keeps the selected column order in
+// every output format, which plain structs or maps cannot do for json/yaml.
+type projectedRows struct {
+	headers []string
+	cells   [][]string
+	values  [][]any
+}
+
+func projectRows(headers []string, records []rowRecord, cols []int) projectedRows {
+	if cols == nil {
+		cols = make([]int, len(headers))
+		for i := range headers {
+			cols[i] = i
+		}
+	}
+	p := projectedRows{}
+	for _, c := range cols {
+		p.headers = append(p.headers, headers[c])
+	}
+	for _, rec := range records {
+		cells := make([]string, 0, len(cols))
+		values := make([]any, 0, len(cols))
+		for _, c := range cols {
+			cells = append(cells, rec.cells[c])
+			values = append(values, rec.values[c])
+		}
+		p.cells = append(p.cells, cells)
+		p.values = append(p.values, values)
+	}
+	return p
+}
+
+func (p projectedRows) tableHeaders() []string { return p.headers }
+
+func (p projectedRows) tableRows() [][]string { return p.cells }
+
+func (p projectedRows) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, row := range p.values {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for j, h := range p.headers {
+			if j > 0 {
+				buf.WriteByte(',')
+			}
+			k, _ := json.Marshal(h)
+			buf.Write(k)
+			buf.WriteByte(':')
+			b, err := json.Marshal(row[j])
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(b)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+func (p projectedRows) MarshalYAML() (any, error) {
+	seq := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, row := range p.values {
+		m := &yaml.Node{Kind: yaml.MappingNode}
+		for j, h := range p.headers {
+			var val yaml.Node
+			if err := val.Encode(row[j]); err != nil {
+				return nil, err
+			}
+			m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: h}, &val)
+		}
+		seq.Content = append(seq.Content, m)
+	}
+	return seq, nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRowOptionsWhere(t *testing.T) {
+	tests := []struct {
+		name  string
+		where string
+		want  rowFilter
+	}{
+		{
+			name:  "single condition",
+			where: "popularity>=30",
+			want:  rowFilter{{{"popularity", ">=", "30"}}},
+		},
+		{
+			name:  "= is ==",
+			where: "country = US",
+			want:  rowFilter{{{"country", "==", "US"}}},
+		},
+		{
+			name:  "&& binds tighter than ||",
+			where: "a==1 && b==2 || c==3",
+			want:  rowFilter{{{"a", "==", "1"}, {"b", "==", "2"}}, {{"c", "==", "3"}}},
+		},
+		{
+			name:  "|| then &&",
+			where: "a==1 || b==2 && c!=3",
+			want:  rowFilter{{{"a", "==", "1"}}, {{"b", "==", "2"}, {"c", "!=", "3"}}},
+		},
+		{
+			name:  "double-quoted value with spaces",
+			where: `keyword=="photo editor"`,
+			want:  rowFilter{{{"keyword", "==", "photo editor"}}},
+		},
+		{
+			name:  "quoted operators are part of the value",
+			where: `keyword=~'a && b' || keyword=~"c || d"`,
+			want:  rowFilter{{{"keyword", "=~", "a && b"}}, {{"keyword", "=~", "c || d"}}},
+		},
+		{
+			name:  "regex-like ops",
+			where: "term!~free&&term=~photo",
+			want:  rowFilter{{{"term", "!~", "free"}, {"term", "=~", "photo"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseRowOptions("", "", tt.where)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(opts.where, tt.want) {
+				t.Errorf("where = %v, want %v", opts.where, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRowOptionsErrors(t *testing.T) {
+	tests := []struct {
+		name, sort, where, wantErr string
+	}{
+		{"missing operator", "", "popularity", "invalid --where condition"},
+		{"empty side of &&", "", "a==1 &&", "invalid --where condition"},
+		{"empty side of ||", "", "|| a==1", "invalid --where condition"},
+		{"missing column", "", ">=3", "invalid --where condition"},
+		{"bad sort direction", "popularity:up", "", "direction must be asc or desc"},
+		{"empty sort column", "-", "", "invalid --sort key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRowOptions("", tt.sort, tt.where)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseRowOptionsSortAndColumns(t *testing.T) {
+	opts, err := parseRowOptions(" keyword, ,popularity ", "-popularity,+keyword,country:desc,term:asc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"keyword", "popularity"}; !reflect.DeepEqual(opts.columns, want) {
+		t.Errorf("columns = %q, want %q", opts.columns, want)
+	}
+	want := []rowSortKey{{"popularity", true}, {"keyword", false}, {"country", true}, {"term", false}}
+	if !reflect.DeepEqual(opts.sort, want) {
+		t.Errorf("sort = %v, want %v", opts.sort, want)
+	}
+}
+
+func TestApplyRowOptions(t *testing.T) {
+	pop := func(n int) *int { return &n }
+	rows := []asoPopscoreRow{
+		{Keyword: "photo", Country: "US", Popularity: pop(40)},
+		{Keyword: "photo", Country: "GB", Popularity: pop(20)},
+		{Keyword: "editor", Country: "US"},
+		{Keyword: "editor", Country: "GB", Popularity: pop(55)},
+	}
+	tests := []struct {
+		name, sort, where string
+		want              []string // keyword/country
+	}{
+		{"numeric filter skips nulls", "", "popularity>=30", []string{"photo/US", "editor/GB"}},
+		{"or of ands", "", "country==us && popularity>30 || keyword==editor && country==GB", []string{"photo/US", "editor/GB"}},
+		{"!= matches nulls", "", "popularity!=40", []string{"photo/GB", "editor/US", "editor/GB"}},
+		{"sort desc, nulls last", "-popularity", "", []string{"editor/GB", "photo/US", "photo/GB", "editor/US"}},
+		{"multi-key sort", "keyword,country", "", []string{"editor/GB", "editor/US", "photo/GB", "photo/US"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseRowOptions("", tt.sort, tt.where)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := applyRowOptions(rows, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, r := range out.([]asoPopscoreRow) {
+				got = append(got, r.Keyword+"/"+r.Country)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	opts, _ := parseRowOptions("", "", "nope==1")
+	if _, err := applyRowOptions(rows, opts); err == nil || !strings.Contains(err.Error(), "unknown column") {
+		t.Errorf("unknown column: err = %v", err)
+	}
+}
+
+func TestApplyRowOptionsKeepsMatrix(t *testing.T) {
+	pop := func(n int) *int { return &n }
+	m := asoMatrix{
+		Countries: []string{"US", "GB"},
+		Rows: []asoMatrixRow{
+			{Keyword: "a", Popularity: map[string]*int{"US": pop(10), "GB": pop(50)}},
+			{Keyword: "b", Popularity: map[string]*int{"US": pop(60)}},
+			{Keyword: "c", Popularity: map[string]*int{"US": pop(30), "GB": pop(5)}},
+		},
+	}
+	opts, err := parseRowOptions("", "-US", "US>=20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := applyRowOptions(m, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := out.(asoMatrix)
+	if !ok {
+		t.Fatalf("applyRowOptions returned %T, want asoMatrix", out)
+	}
+	if !reflect.DeepEqual(got.Countries, m.Countries) {
+		t.Errorf("countries = %q", got.Countries)
+	}
+	var keywords []string
+	for _, r := range got.Rows {
+		keywords = append(keywords, r.Keyword)
+	}
+	if want := []string{"b", "c"}; !reflect.DeepEqual(keywords, want) {
+		t.Errorf("keywords = %q, want %q", keywords, want)
+	}
+
+	opts, _ = parseRowOptions("keyword,US", "", "")
+	if out, _ := applyRowOptions(m, opts); reflect.TypeOf(out) != reflect.TypeOf(projectedRows{}) {
+		t.Errorf("--columns returned %T, want projectedRows", out)
+	}
+}
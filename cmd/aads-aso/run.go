@@ -0,0 +1,147 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// runInfo describes the current command run. Commands fill it in as they resolve
+// things (adam-id, app name) so that reports can show what produced the rows.
+type runInfo struct {
+	ID           string
+	Command      string
+	StartedAt    time.Time
+	AdamID       int64
+	AdamIDSource string
+	AppName      string
+	Countries    []string
+	Storefront   string
+	Seed         string
+	Timings      []runCountryTiming
+	Warnings     []string
+	Flags        []runFlag
+}
+
+// runFlag is a flag that was set for the run. Source names the env var or config layer
+// the value came from and is empty for command-line flags.
+type runFlag struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source,omitempty"`
+}
+
+// runCountryTiming is the wall time spent on one country's requests.
+type runCountryTiming struct {
+	Country    string `json:"country" yaml:"country"`
+	DurationMS int64  `json:"durationMs" yaml:"durationMs"`
+}
+
+// Values for runInfo.AdamIDSource.
+const (
+	adamIDSourceFlag          = "adam-id"
+	adamIDSourceAppURL        = "app-url"
+	adamIDSourceBundleID      = "bundle-id"
+	adamIDSourceAppName       = "app-name"
+	adamIDSourceOwnedCampaign = "owned-campaigns"
+	adamIDSourceOwnedFallback = "owned-fallback"
+)
+
+var currentRun runInfo
+
+// Flags whose values are secrets and must never end up in reports.
+var redactedRunFlags = map[string]bool{
+	"cookie":      true,
+	"cookie-exec": true, // shell command, often with a secrets-manager token
+	"header":      true,
+}
+
+func startRun(cmd *cobra.Command) {
+	now := time.Now().UTC()
+	currentRun = runInfo{
+		ID:        newRunID(now),
+		Command:   cmd.CommandPath(),
+		StartedAt: now,
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		source := flagConfigSource(f)
+		if !f.Changed && source == "" {
+			return
+		}
+		value := f.Value.String()
+		if redactedRunFlags[f.Name] {
+			value = "<redacted>"
+		}
+		currentRun.Flags = append(currentRun.Flags, runFlag{Name: f.Name, Value: value, Source: source})
+	})
+	sort.Slice(currentRun.Flags, func(i, j int) bool {
+		return currentRun.Flags[i].Name < currentRun.Flags[j].Name
+	})
+}
+
+// newRunID returns a sortable, practically unique identifier such as
+// 20261018T114233Z-3f9a1c2e.
+func newRunID(now time.Time) string {
+	var suffix [4]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return fmt.Sprintf("%s-%d", now.Format("20060102T150405Z"), os.Getpid())
+	}
+	return now.Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix[:])
+}
+
+func recordRunAdamID(adamID int64, source, appName string) {
+	if adamID != currentRun.AdamID {
+		currentRun.AppName = ""
+	}
+	currentRun.AdamID = adamID
+	currentRun.AdamIDSource = source
+	if strings.TrimSpace(appName) != "" {
+		currentRun.AppName = strings.TrimSpace(appName)
+	}
+}
+
+func recordRunCountries(countries []string) {
+	currentRun.Countries = append([]string(nil), countries...)
+}
+
+func recordRunStorefront(storefront string) {
+	currentRun.Storefront = storefront
+}
+
+func recordRunSeed(seed string) {
+	currentRun.Seed = seed
+}
+
+// trackRunCountry starts timing work for one country; call the returned func with
+// the outcome when it is done. Repeated calls for the same country add up.
+func trackRunCountry(country string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		d := time.Since(start).Milliseconds()
+		if err != nil {
+			logDebug("country_failed", fmt.Sprintf("%s failed after %dms: %v", country, d, err), "country", country, "duration_ms", d, "error", err.Error())
+		} else {
+			logDebug("country_done", fmt.Sprintf("%s done in %dms", country, d), "country", country, "duration_ms", d)
+		}
+		for i := range currentRun.Timings {
+			if currentRun.Timings[i].Country == country {
+				currentRun.Timings[i].DurationMS += d
+				return
+			}
+		}
+		currentRun.Timings = append(currentRun.Timings, runCountryTiming{Country: country, DurationMS: d})
+	}
+}
+
+// warnRun logs a warning event and keeps its message for the --envelope metadata.
+func warnRun(event, msg string, attrs ...any) {
+	currentRun.Warnings = append(currentRun.Warnings, msg)
+	logWarn(event, msg, attrs...)
+}
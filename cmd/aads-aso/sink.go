@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var sinkFlags []string
+
+// sqliteSinkTable names the table a row type is written to and the columns (besides
+// run_id) that identify a row within one run.
+type sqliteSinkTable struct {
+	name string
+	keys []string
+}
+
+var sqliteSinkTables = map[reflect.Type]sqliteSinkTable{
+	reflect.TypeOf(asoPopscoreRow{}):    {name: "popscore", keys: []string{"keyword", "country"}},
+	reflect.TypeOf(asoRecommendRow{}):   {name: "recommend", keys: []string{"country", "seed", "term"}},
+	reflect.TypeOf(asoHintRow{}):        {name: "hints", keys: []string{"country", "term"}},
+	reflect.TypeOf(asoDiscoverRow{}):    {name: "discover", keys: []string{"country", "term"}},
+	reflect.TypeOf(asoCampaignRow{}):    {name: "campaigns", keys: []string{"id"}},
+	reflect.TypeOf(asoAppRow{}):         {name: "apps", keys: []string{"adamId"}},
+	reflect.TypeOf(asoAppMetadataRow{}): {name: "app_metadata", keys: []string{"adamId", "country"}},
+}
+
+// writeSinks stores data in every --sink target. Sinks always receive all rows; the
+// --columns, --sort and --where options only shape what is printed.
+func writeSinks(data any) error {
+	for _, raw := range sinkFlags {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		scheme, target, ok := strings.Cut(raw, ":")
+		if !ok || strings.TrimSpace(target) == "" {
+			return fmt.Errorf("invalid --sink %q (expected sqlite:PATH)", raw)
+		}
+		switch strings.ToLower(strings.TrimSpace(scheme)) {
+		case "sqlite", "sqlite3":
+			if err := writeSQLiteSink(context.Background(), strings.TrimSpace(target), data); err != nil {
+				return fmt.Errorf("sink %s: %w", raw, err)
+			}
+		default:
+			return fmt.Errorf("unsupported --sink scheme %q (supported: sqlite)", scheme)
+		}
+	}
+	return nil
+}
+
+// writeSQLiteSink upserts rows into the SQLite database at path (pure-Go driver, no
+// cgo). Values are bound as statement parameters; only identifiers are quoted.
+func writeSQLiteSink(ctx context.Context, path string, data any) error {
+	headers, records, ok := collectRowRecords(data)
+	if !ok {
+		return fmt.Errorf("sqlite sink needs a list of rows")
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("create database dir: %w", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS runs (
+  run_id TEXT PRIMARY KEY,
+  started_at TEXT NOT NULL,
+  finished_at TEXT,
+  command TEXT,
+  adam_id INTEGER,
+  app_name TEXT,
+  flags TEXT
+)`); err != nil {
+		return err
+	}
+	flagsJSON, _ := json.Marshal(currentRun.Flags)
+	if _, err := tx.ExecContext(ctx, `INSERT INTO runs (run_id, started_at, finished_at, command, adam_id, app_name, flags) VALUES (?, ?, ?, ?, ?, ?, ?)
+  ON CONFLICT(run_id) DO UPDATE SET finished_at=excluded.finished_at, adam_id=excluded.adam_id, app_name=excluded.app_name`,
+		currentRun.ID,
+		currentRun.StartedAt.Format(time.RFC3339),
+		time.Now().UTC().Format(time.RFC3339),
+		currentRun.Command,
+		sqlNullableInt(currentRun.AdamID),
+		sqlNullableString(currentRun.AppName),
+		string(flagsJSON),
+	); err != nil {
+		return err
+	}
+
+	if len(headers) > 0 {
+		table := sqliteSinkTableFor(data)
+		keys := table.keys
+		if len(keys) == 0 {
+			keys = []string{"row_num"}
+		}
+
+		cols := []string{"run_id TEXT NOT NULL", "recorded_at TEXT NOT NULL", "adam_id INTEGER"}
+		if table.keys == nil {
+			cols = append(cols, "row_num INTEGER NOT NULL")
+		}
+		for i, h := range headers {
+			cols = append(cols, sqlIdent(h)+" "+sqliteColumnType(records, i))
+		}
+		keyIdents := []string{"run_id"}
+		for _, k := range keys {
+			keyIdents = append(keyIdents, sqlIdent(k))
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n  %s,\n  PRIMARY KEY (%s)\n)",
+			sqlIdent(table.name), strings.Join(cols, ",\n  "), strings.Join(keyIdents, ", "))); err != nil {
+			return err
+		}
+
+		// Tables created by earlier runs may lack columns that appeared since (new
+		// countries in a matrix, new row fields); add them rather than failing.
+		existing, err := sqliteTableColumns(ctx, tx, table.name)
+		if err != nil {
+			return err
+		}
+		for i, h := range headers {
+			if !containsFold(existing, h) {
+				if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", sqlIdent(table.name), sqlIdent(h), sqliteColumnType(records, i))); err != nil {
+					return err
+				}
+			}
+		}
+
+		insertCols := []string{"run_id", "recorded_at", "adam_id"}
+		if table.keys == nil {
+			insertCols = append(insertCols, "row_num")
+		}
+		var updates []string
+		for _, h := range headers {
+			insertCols = append(insertCols, sqlIdent(h))
+			if !containsFold(keys, h) {
+				updates = append(updates, sqlIdent(h)+"=excluded."+sqlIdent(h))
+			}
+		}
+		updates = append(updates, "recorded_at=excluded.recorded_at", "adam_id=excluded.adam_id")
+
+		stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)\n  ON CONFLICT(%s) DO UPDATE SET %s",
+			sqlIdent(table.name), strings.Join(insertCols, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(insertCols)), ", "),
+			strings.Join(keyIdents, ", "), strings.Join(updates, ", ")))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		recordedAt := time.Now().UTC().Format(time.RFC3339)
+		for n, rec := range records {
+			args := []any{currentRun.ID, recordedAt, sqlNullableInt(currentRun.AdamID)}
+			if table.keys == nil {
+				args = append(args, n+1)
+			}
+			for _, v := range rec.values {
+				args = append(args, sqlValue(v))
+			}
+			if _, err := stmt.ExecContext(ctx, args...); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// sqliteSinkTableFor returns the configured table for known row types. Other results
+// go to a table named after the command, keyed by row number.
+func sqliteSinkTableFor(data any) sqliteSinkTable {
+	v := indirectValue(reflect.ValueOf(data))
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		t := v.Type().Elem()
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if table, ok := sqliteSinkTables[t]; ok {
+			return table
+		}
+	}
+
+	name := "results"
+	if fields := strings.Fields(currentRun.Command); len(fields) > 0 {
+		name = strings.ReplaceAll(fields[len(fields)-1], "-", "_")
+	}
+	return sqliteSinkTable{name: name}
+}
+
+func sqliteColumnType(records []rowRecord, col int) string {
+	for _, rec := range records {
+		v := rec.values[col]
+		if v == nil {
+			continue
+		}
+		switch reflect.ValueOf(v).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Bool:
+			return "INTEGER"
+		case reflect.Float32, reflect.Float64:
+			return "REAL"
+		default:
+			return "TEXT"
+		}
+	}
+	return "TEXT"
+}
+
+// sqliteTableColumns lists the columns of table, or nothing when it does not exist yet.
+func sqliteTableColumns(ctx context.Context, tx *sql.Tx, table string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cols []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		cols = append(cols, name)
+	}
+	return cols, rows.Err()
+}
+
+func sqlIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func sqlNullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func sqlNullableInt(n int64) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// sqlValue converts a row value to a statement argument: lists and objects are
+// stored as JSON text, booleans as 0/1.
+func sqlValue(v any) any {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.String:
+		return rv.String()
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, it := range list {
+		if strings.EqualFold(it, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteSQLiteSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "aso.db")
+	currentRun = runInfo{ID: "run-1", Command: "aads-aso popscore", AdamID: 42}
+	t.Cleanup(func() { currentRun = runInfo{} })
+
+	pop := func(n int) *int { return &n }
+	rows := []asoPopscoreRow{
+		{Keyword: "it's \"quoted\"; DROP TABLE runs; --", Country: "US", Popularity: pop(40), Found: true},
+		{Keyword: "photo", Country: "US"},
+	}
+	ctx := context.Background()
+	if err := writeSQLiteSink(ctx, path, rows); err != nil {
+		t.Fatal(err)
+	}
+	// Same keys again in the same run update the rows instead of failing.
+	rows[1].Popularity = pop(7)
+	if err := writeSQLiteSink(ctx, path, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var runs int
+	if err := db.QueryRow(`SELECT count(*) FROM runs WHERE run_id = ? AND adam_id = 42`, "run-1").Scan(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 {
+		t.Errorf("runs = %d, want 1", runs)
+	}
+
+	got := map[string]sql.NullInt64{}
+	found := map[string]int{}
+	r, err := db.Query(`SELECT keyword, popularity, found FROM popscore WHERE run_id = ?`, "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for r.Next() {
+		var kw string
+		var p sql.NullInt64
+		var f int
+		if err := r.Scan(&kw, &p, &f); err != nil {
+			t.Fatal(err)
+		}
+		got[kw], found[kw] = p, f
+	}
+	if len(got) != 2 {
+		t.Fatalf("rows = %v, want 2", got)
+	}
+	if p := got[rows[0].Keyword]; !p.Valid || p.Int64 != 40 || found[rows[0].Keyword] != 1 {
+		t.Errorf("quoted keyword row = %v found=%d", p, found[rows[0].Keyword])
+	}
+	if p := got["photo"]; !p.Valid || p.Int64 != 7 {
+		t.Errorf("photo popularity = %v, want updated 7", p)
+	}
+}
+
+func TestWriteSQLiteSinkAddsColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aso.db")
+	currentRun = runInfo{ID: "run-1", Command: "aads-aso matrix"}
+	t.Cleanup(func() { currentRun = runInfo{} })
+
+	pop := func(n int) *int { return &n }
+	ctx := context.Background()
+	first := asoMatrix{Countries: []string{"US"}, Rows: []asoMatrixRow{{Keyword: "a", Popularity: map[string]*int{"US": pop(5)}}}}
+	if err := writeSQLiteSink(ctx, path, first); err != nil {
+		t.Fatal(err)
+	}
+	currentRun.ID = "run-2"
+	second := asoMatrix{Countries: []string{"US", "GB"}, Rows: []asoMatrixRow{{Keyword: "a", Popularity: map[string]*int{"GB": pop(9)}}}}
+	if err := writeSQLiteSink(ctx, path, second); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var gb sql.NullInt64
+	if err := db.QueryRow(`SELECT "GB" FROM matrix WHERE run_id = 'run-2'`).Scan(&gb); err != nil {
+		t.Fatal(err)
+	}
+	if !gb.Valid || gb.Int64 != 9 {
+		t.Errorf("GB = %v, want 9", gb)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Light suffix-stripping stemmers. They are intentionally simple: the goal is to
+// group keyword variants ("photo editor", "photo editing"), not linguistic accuracy.
+
+type stemRule struct {
+	suffix      string
+	replacement string
+}
+
+var stemRulesByLang = map[string][]stemRule{
+	"en": {
+		{"ational", "ate"}, {"ization", "ize"}, {"iveness", "ive"}, {"fulness", "ful"},
+		{"ousness", "ous"}, {"ifiers", "ify"}, {"ifier", "ify"}, {"ations", "ate"},
+		{"ation", "ate"}, {"ities", ""}, {"ity", ""}, {"ingly", ""}, {"ings", ""},
+		{"ing", ""}, {"edly", ""}, {"ers", ""}, {"er", ""}, {"ies", "y"}, {"ied", "y"},
+		{"ed", ""}, {"ly", ""}, {"s", ""},
+	},
+	"de": {
+		{"ungen", ""}, {"heiten", ""}, {"keiten", ""}, {"heit", ""}, {"keit", ""},
+		{"ung", ""}, {"isch", ""}, {"lich", ""}, {"ern", ""}, {"em", ""}, {"en", ""},
+		{"er", ""}, {"es", ""}, {"e", ""}, {"s", ""}, {"n", ""},
+	},
+	"fr": {
+		{"issements", ""}, {"issement", ""}, {"ations", ""}, {"ation", ""}, {"ements", ""},
+		{"ement", ""}, {"ments", ""}, {"ment", ""}, {"trices", ""}, {"trice", ""},
+		{"teurs", ""}, {"teur", ""}, {"euses", ""}, {"euse", ""}, {"eux", ""},
+		{"aux", "al"}, {"ees", ""}, {"ee", ""}, {"es", ""}, {"e", ""}, {"s", ""}, {"x", ""},
+	},
+	"es": {
+		{"aciones", ""}, {"acion", ""}, {"amientos", ""}, {"amiento", ""}, {"idades", ""},
+		{"idad", ""}, {"mente", ""}, {"istas", ""}, {"ista", ""}, {"ores", ""}, {"or", ""},
+		{"es", ""}, {"os", ""}, {"as", ""}, {"s", ""}, {"o", ""}, {"a", ""}, {"e", ""},
+	},
+}
+
+var stopwordsByLang = map[string]map[string]bool{
+	"en": wordSet("a an and the for of to in on with by my your from at or"),
+	"de": wordSet("der die das den dem des und fur mit ein eine einen von zu im in am auf oder"),
+	"fr": wordSet("le la les l de des du d et pour un une en au aux avec sur ou"),
+	"es": wordSet("el la los las de del y para un una en con por al o"),
+}
+
+// Minimum number of characters kept after stripping a suffix.
+const minStemLength = 3
+
+var accentFolder = strings.NewReplacer(
+	"ä", "a", "ö", "o", "ü", "u", "ß", "ss",
+	"à", "a", "á", "a", "â", "a", "ã", "a", "å", "a",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ù", "u", "ú", "u", "û", "u",
+	"ý", "y", "ÿ", "y", "œ", "oe", "æ", "ae",
+)
+
+func wordSet(words string) map[string]bool {
+	out := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		out[w] = true
+	}
+	return out
+}
+
+// languageForCountry maps a storefront to the stemming language used by default.
+// Anything not listed falls back to English.
+func languageForCountry(country string) string {
+	switch strings.ToUpper(strings.TrimSpace(country)) {
+	case "DE", "AT", "CH", "LI", "LU":
+		return "de"
+	case "FR", "BE", "MC", "SN", "CI", "CM", "MG", "ML", "NE", "BF":
+		return "fr"
+	case "ES", "MX", "AR", "CO", "CL", "PE", "VE", "EC", "GT", "BO", "DO", "HN", "PY", "SV", "NI", "CR", "PA", "UY":
+		return "es"
+	default:
+		return "en"
+	}
+}
+
+// keywordTokens splits a keyword into lowercase, accent-folded words.
+func keywordTokens(s string) []string {
+	s = accentFolder.Replace(strings.ToLower(strings.TrimSpace(s)))
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// keywordStems returns the stems of the non-stopword tokens of s. Unknown languages
+// (or "none") only fold case and accents.
+func keywordStems(s, lang string) []string {
+	stop := stopwordsByLang[lang]
+	var out []string
+	for _, tok := range keywordTokens(s) {
+		if stop[tok] {
+			continue
+		}
+		out = append(out, stemWord(tok, lang))
+	}
+	return out
+}
+
+func stemWord(w, lang string) string {
+	rules := stemRulesByLang[lang]
+	for _, r := range rules {
+		if !strings.HasSuffix(w, r.suffix) {
+			continue
+		}
+		stem := strings.TrimSuffix(w, r.suffix)
+		if len([]rune(stem)) < minStemLength {
+			continue
+		}
+		if lang == "en" && r.suffix == "s" && (strings.HasSuffix(stem, "s") || strings.HasSuffix(stem, "u") || strings.HasSuffix(stem, "i")) {
+			// Keep "class", "status", "analysis" intact.
+			continue
+		}
+		w = stem + r.replacement
+		break
+	}
+	if lang == "en" && len([]rune(w)) > minStemLength {
+		w = strings.TrimSuffix(w, "e")
+	}
+	return w
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStemWord(t *testing.T) {
+	tests := []struct {
+		lang, word, want string
+	}{
+		{"en", "editing", "edit"},
+		{"en", "editors", "editor"},
+		{"en", "photos", "photo"},
+		{"en", "class", "class"},
+		{"en", "status", "status"},
+		{"en", "stories", "story"},
+		{"en", "ads", "ads"},
+		{"de", "rechnungen", "rechn"},
+		{"de", "freiheit", "frei"},
+		{"fr", "traitement", "trait"},
+		{"fr", "journaux", "journal"},
+		{"es", "aplicaciones", "aplic"},
+		{"es", "fotos", "fot"},
+		{"none", "editing", "editing"},
+	}
+	for _, tt := range tests {
+		if got := stemWord(tt.word, tt.lang); got != tt.want {
+			t.Errorf("stemWord(%q, %q) = %q, want %q", tt.word, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordStems(t *testing.T) {
+	tests := []struct {
+		lang, in string
+		want     []string
+	}{
+		{"en", "The Photo Editor for iPhone", []string{"photo", "editor", "iphon"}},
+		{"de", "Bilder für die Fotos", []string{"bild", "foto"}},
+		{"fr", "Éditeur de photos", []string{"edi", "photo"}},
+		{"es", "Edición de fotos", []string{"edicion", "fot"}},
+		{"en", "  ", nil},
+	}
+	for _, tt := range tests {
+		if got := keywordStems(tt.in, tt.lang); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("keywordStems(%q, %q) = %q, want %q", tt.in, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordTokens(t *testing.T) {
+	got := keywordTokens("  Café-Bar: Über 2 Mal! ")
+	want := []string{"cafe", "bar", "uber", "2", "mal"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("keywordTokens = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var templateFile string
+
+// templateGroup is one bucket produced by the groupBy template helper.
+type templateGroup struct {
+	Key  string
+	Rows []any
+}
+
+// printTemplate renders data through a text/template file. Rows are exposed as maps
+// keyed by their JSON names (the same names --columns and --where use), so a template
+// reads {{range .}}{{.keyword}} {{pop .popularity}}{{end}} for any command.
+func printTemplate(w io.Writer, data any, path string) error {
+	if strings.TrimSpace(path) == "" {
+		return fmt.Errorf("--template is required with --output template")
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read template: %w", err)
+	}
+	tmpl, err := template.New(path).Funcs(templateFuncs()).Parse(string(src))
+	if err != nil {
+		return fmt.Errorf("parse template: %w", err)
+	}
+
+	generic, err := templateData(data)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, generic)
+}
+
+func templateData(data any) (any, error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"pop": templatePopularity,
+		"sortBy": func(key string, rows []any) []any {
+			return templateSort(key, rows, false)
+		},
+		"sortByDesc": func(key string, rows []any) []any {
+			return templateSort(key, rows, true)
+		},
+		"groupBy": templateGroupBy,
+		"field":   templateField,
+		"join": func(sep string, v any) string {
+			items, _ := v.([]any)
+			parts := make([]string, 0, len(items))
+			for _, it := range items {
+				parts = append(parts, fmt.Sprint(it))
+			}
+			return strings.Join(parts, sep)
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"default": func(def, v any) any {
+			if v == nil || v == "" {
+				return def
+			}
+			return v
+		},
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"now": func() string {
+			return time.Now().UTC().Format(time.RFC3339)
+		},
+	}
+}
+
+// templatePopularity formats a popularity value, printing "-" when it is unknown.
+func templatePopularity(v any) string {
+	switch p := v.(type) {
+	case nil:
+		return "-"
+	case float64:
+		return strconv.FormatFloat(p, 'f', -1, 64)
+	default:
+		return fmt.Sprint(p)
+	}
+}
+
+func templateField(key string, row any) any {
+	m, _ := row.(map[string]any)
+	return m[key]
+}
+
+func templateSort(key string, rows []any, desc bool) []any {
+	out := append([]any(nil), rows...)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := templateField(key, out[i]), templateField(key, out[j])
+		if a == nil || b == nil {
+			// Missing values last, regardless of direction.
+			return a != nil && b == nil
+		}
+		af, aNum := a.(float64)
+		bf, bNum := b.(float64)
+		var c int
+		if aNum && bNum {
+			c = compareFloats(af, bf)
+		} else {
+			c = strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	return out
+}
+
+// templateGroupBy buckets rows by a field, in order of first appearance.
+func templateGroupBy(key string, rows []any) []templateGroup {
+	var out []templateGroup
+	index := map[string]int{}
+	for _, r := range rows {
+		k := ""
+		if v := templateField(key, r); v != nil {
+			k = fmt.Sprint(v)
+		}
+		i, ok := index[k]
+		if !ok {
+			i = len(out)
+			index[k] = i
+			out = append(out, templateGroup{Key: k})
+		}
+		out[i].Rows = append(out[i].Rows, r)
+	}
+	return out
+}
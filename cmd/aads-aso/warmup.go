@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// The keyword endpoints sometimes keep rejecting a fresh login until an ad group's
+// Add Keywords page has been opened once in the browser. The warm-up does that before
+// the cookies are exported.
+const (
+	defaultWarmUpURL      = "https://app-ads.apple.com/cm/app/campaigns/{campaignId}/adgroups/{adGroupId}/keywords/add"
+	warmUpTimeout         = 45 * time.Second
+	warmUpSettle          = 3 * time.Second // let the page's own API calls set their cookies
+	warmUpCampaignsToScan = 10              // campaigns asked for an ad group before giving up
+)
+
+func addWarmUpFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("warm-up", true, "After login, open an ad group's Add Keywords page before exporting cookies (on by default; avoids keyword calls failing with refresh errors)")
+	cmd.Flags().String("warm-up-url", defaultWarmUpURL, "Page opened by --warm-up; {campaignId}, {adGroupId} and {adamId} are filled from the first campaign with an ad group")
+}
+
+func getWarmUpFlags(cmd *cobra.Command) (bool, string) {
+	enabled, _ := cmd.Flags().GetBool("warm-up")
+	url, _ := cmd.Flags().GetString("warm-up-url")
+	return enabled, strings.TrimSpace(url)
+}
+
+// warmUpCMSession opens the Add Keywords page of the first ad group it finds with
+// navigate and returns the cookies read afterwards. Warm-up is best effort: when a
+// step fails it warns and returns the cookies it was given.
+func warmUpCMSession(
+	ctx context.Context,
+	opts cmCookieRefreshOptions,
+	cookies []exportedCookie,
+	navigate func(ctx context.Context, url string) error,
+	probe func(ctx context.Context) (cmLoginState, error),
+) []exportedCookie {
+	if !opts.WarmUp {
+		return cookies
+	}
+	header := buildCMCookieHeader(cookies, time.Now())
+	if header == "" {
+		return cookies
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, warmUpTimeout)
+	defer cancel()
+
+	campaigns, err := cmCampaignsFind(ctx, header, opts.ExtraHeaders)
+	if err != nil {
+		warnRun("warm_up_skipped", "Session warm-up skipped: could not list campaigns: "+err.Error(), "reason", "campaigns_failed")
+		return cookies
+	}
+	if len(campaigns) == 0 {
+		logInfo("warm_up_skipped", "Session warm-up skipped: the account has no campaigns", "reason", "no_campaigns")
+		return cookies
+	}
+
+	c, g, err := warmUpAdGroup(ctx, campaigns, func(ctx context.Context, campaignID int64) ([]cmAdGroupItem, error) {
+		return cmAdGroupsFind(ctx, header, opts.ExtraHeaders, campaignID)
+	})
+	if err != nil {
+		warnRun("warm_up_skipped", "Session warm-up skipped: "+err.Error(), "reason", "no_ad_group")
+		return cookies
+	}
+	url := warmUpPageURL(opts.WarmUpURL, c, g)
+
+	logInfo("warm_up", fmt.Sprintf("Opening the Add Keywords page of ad group %q (campaign %q) to warm up the session...", g.Name, c.Name),
+		"url", url, "campaign_id", c.ID, "ad_group_id", g.ID, "adam_id", c.AdamID)
+	if err := navigate(ctx, url); err != nil {
+		warnRun("warm_up_skipped", "Session warm-up failed: "+err.Error(), "reason", "navigation_failed", "url", url)
+		return cookies
+	}
+	state, err := probe(ctx)
+	if err != nil || buildCMCookieHeader(state.Cookies, time.Now()) == "" {
+		if err == nil {
+			err = fmt.Errorf("no cookies after opening %s", url)
+		}
+		warnRun("warm_up_skipped", "Session warm-up failed: "+err.Error(), "reason", "cookies_failed", "url", url)
+		return cookies
+	}
+	return state.Cookies
+}
+
+// warmUpAdGroup returns the first of the first warmUpCampaignsToScan campaigns that
+// has an ad group, together with that ad group.
+func warmUpAdGroup(
+	ctx context.Context,
+	campaigns []cmCampaignItem,
+	adGroups func(ctx context.Context, campaignID int64) ([]cmAdGroupItem, error),
+) (cmCampaignItem, cmAdGroupItem, error) {
+	var lastErr error
+	for _, c := range campaigns[:min(len(campaigns), warmUpCampaignsToScan)] {
+		groups, err := adGroups(ctx, c.ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return cmCampaignItem{}, cmAdGroupItem{}, err
+			}
+			lastErr = err
+			logDebug("warm_up_ad_groups_failed", err.Error(), "campaign_id", c.ID)
+			continue
+		}
+		if len(groups) > 0 {
+			return c, groups[0], nil
+		}
+	}
+	if lastErr != nil {
+		return cmCampaignItem{}, cmAdGroupItem{}, fmt.Errorf("could not list ad groups: %w", lastErr)
+	}
+	return cmCampaignItem{}, cmAdGroupItem{}, fmt.Errorf("no ad group found in the first %d campaigns", min(len(campaigns), warmUpCampaignsToScan))
+}
+
+// warmUpPageURL fills the {campaignId}, {adGroupId} and {adamId} placeholders of tmpl
+// (defaultWarmUpURL when empty).
+func warmUpPageURL(tmpl string, c cmCampaignItem, g cmAdGroupItem) string {
+	if tmpl == "" {
+		tmpl = defaultWarmUpURL
+	}
+	return strings.NewReplacer(
+		"{campaignId}", strconv.FormatInt(c.ID, 10),
+		"{adGroupId}", strconv.FormatInt(g.ID, 10),
+		"{adamId}", strconv.FormatInt(c.AdamID, 10),
+	).Replace(tmpl)
+}
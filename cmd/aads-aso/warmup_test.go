@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWarmUpAdGroup(t *testing.T) {
+	campaigns := []cmCampaignItem{{ID: 1, AdamID: 100}, {ID: 2, AdamID: 200}, {ID: 3, AdamID: 300}}
+	tests := []struct {
+		name         string
+		campaigns    []cmCampaignItem
+		groups       map[int64][]cmAdGroupItem
+		fail         map[int64]bool
+		wantCampaign int64
+		wantGroup    int64
+		wantErr      string
+	}{
+		{
+			name:         "first campaign with an ad group",
+			campaigns:    campaigns,
+			groups:       map[int64][]cmAdGroupItem{2: {{ID: 20}, {ID: 21}}, 3: {{ID: 30}}},
+			wantCampaign: 2, wantGroup: 20,
+		},
+		{
+			name:         "lookup errors are skipped",
+			campaigns:    campaigns,
+			groups:       map[int64][]cmAdGroupItem{3: {{ID: 30}}},
+			fail:         map[int64]bool{1: true, 2: true},
+			wantCampaign: 3, wantGroup: 30,
+		},
+		{name: "no ad groups", campaigns: campaigns, wantErr: "no ad group found in the first 3 campaigns"},
+		{name: "every lookup failed", campaigns: campaigns, fail: map[int64]bool{1: true, 2: true, 3: true}, wantErr: "could not list ad groups: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, g, err := warmUpAdGroup(context.Background(), tt.campaigns, func(ctx context.Context, id int64) ([]cmAdGroupItem, error) {
+				if tt.fail[id] {
+					return nil, errors.New("boom")
+				}
+				return tt.groups[id], nil
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.ID != tt.wantCampaign || g.ID != tt.wantGroup {
+				t.Errorf("got campaign %d, ad group %d; want %d, %d", c.ID, g.ID, tt.wantCampaign, tt.wantGroup)
+			}
+		})
+	}
+
+	// Only the first warmUpCampaignsToScan campaigns are asked.
+	many := make([]cmCampaignItem, warmUpCampaignsToScan+5)
+	calls := 0
+	_, _, _ = warmUpAdGroup(context.Background(), many, func(context.Context, int64) ([]cmAdGroupItem, error) {
+		calls++
+		return nil, nil
+	})
+	if calls != warmUpCampaignsToScan {
+		t.Errorf("asked %d campaigns, want %d", calls, warmUpCampaignsToScan)
+	}
+}
+
+func TestWarmUpPageURL(t *testing.T) {
+	c := cmCampaignItem{ID: 11, AdamID: 1234567890}
+	g := cmAdGroupItem{ID: 22, CampaignID: 11}
+	tests := []struct{ tmpl, want string }{
+		{"", "https://app-ads.apple.com/cm/app/campaigns/11/adgroups/22/keywords/add"},
+		{"https://example.com/{adamId}/{campaignId}/{adGroupId}", "https://example.com/1234567890/11/22"},
+	}
+	for _, tt := range tests {
+		if got := warmUpPageURL(tt.tmpl, c, g); got != tt.want {
+			t.Errorf("warmUpPageURL(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Minimal Office Open XML writer for --output xlsx. It only knows what keyword reports
+// need: inline strings, numbers, booleans, a bold frozen header row and autofilters.
+
+// checkBinaryOutput refuses --output xlsx when stdout is a terminal, before any request
+// is made: a zip archive on the screen is garbage and can leave the terminal in a bad state.
+func checkBinaryOutput(format string, stdoutTerminal bool) error {
+	if strings.EqualFold(strings.TrimSpace(format), "xlsx") && stdoutTerminal {
+		return fmt.Errorf("--output xlsx writes a binary workbook; redirect stdout to a file (e.g. > report.xlsx)")
+	}
+	return nil
+}
+
+func stdoutIsTerminal() bool {
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+type xlsxSheet struct {
+	Name    string
+	Headers []string
+	Rows    [][]any
+}
+
+func printXLSX(w io.Writer, data any) error {
+	headers, records, ok := collectRowRecords(data)
+	if !ok {
+		return fmt.Errorf("xlsx output needs a list of rows")
+	}
+
+	countryCol := -1
+	popCol := -1
+	foundCol := -1
+	for i, h := range headers {
+		switch strings.ToLower(h) {
+		case "country":
+			countryCol = i
+		case "popularity":
+			popCol = i
+		case "found":
+			foundCol = i
+		}
+	}
+
+	var sheets []*xlsxSheet
+	byName := map[string]*xlsxSheet{}
+	for _, rec := range records {
+		name := "Results"
+		if countryCol >= 0 && rec.cells[countryCol] != "" {
+			name = rec.cells[countryCol]
+		}
+		sh, ok := byName[name]
+		if !ok {
+			sh = &xlsxSheet{Name: xlsxSheetName(name, len(sheets)+2), Headers: headers}
+			byName[name] = sh
+			sheets = append(sheets, sh)
+		}
+		row := make([]any, len(rec.values))
+		for i, v := range rec.values {
+			row[i] = xlsxCellValue(v, rec.cells[i])
+		}
+		sh.Rows = append(sh.Rows, row)
+	}
+
+	summary := &xlsxSheet{
+		Name:    "Summary",
+		Headers: []string{"sheet", "rows"},
+	}
+	if foundCol >= 0 {
+		summary.Headers = append(summary.Headers, "found")
+	}
+	if popCol >= 0 {
+		summary.Headers = append(summary.Headers, "withPopularity", "averagePopularity", "maxPopularity")
+	}
+	for _, sh := range sheets {
+		row := []any{sh.Name, len(sh.Rows)}
+		if foundCol >= 0 {
+			found := 0
+			for _, r := range sh.Rows {
+				if b, ok := r[foundCol].(bool); ok && b {
+					found++
+				}
+			}
+			row = append(row, found)
+		}
+		if popCol >= 0 {
+			n, sum, maxPop := 0, 0.0, math.Inf(-1)
+			for _, r := range sh.Rows {
+				f, ok := r[popCol].(float64)
+				if !ok {
+					continue
+				}
+				n++
+				sum += f
+				maxPop = math.Max(maxPop, f)
+			}
+			if n > 0 {
+				row = append(row, n, math.Round(sum/float64(n)*10)/10, maxPop)
+			} else {
+				row = append(row, 0, nil, nil)
+			}
+		}
+		summary.Rows = append(summary.Rows, row)
+	}
+
+	return writeXLSX(w, append([]*xlsxSheet{summary}, sheets...))
+}
+
+// xlsxCellValue keeps numbers and booleans typed; everything else is written as text.
+func xlsxCellValue(v any, cell string) any {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	if cell == "" {
+		return nil
+	}
+	return cell
+}
+
+// xlsxSheetName makes a valid, unique-enough worksheet name (max 31 chars, no []:*?/\).
+func xlsxSheetName(name string, n int) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || strings.EqualFold(name, "Summary") {
+		name = "Sheet" + strconv.Itoa(n)
+	}
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	return name
+}
+
+func writeXLSX(w io.Writer, sheets []*xlsxSheet) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes(len(sheets))},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook(sheets)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(sheets))},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, f := range files {
+		if err := xlsxWriteFile(zw, f.name, f.body); err != nil {
+			return err
+		}
+	}
+	for i, sh := range sheets {
+		if err := xlsxWriteFile(zw, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), xlsxWorksheet(sh)); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func xlsxWriteFile(zw *zip.Writer, name, body string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, body)
+	return err
+}
+
+const xlsxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+
+const xlsxRootRels = xlsxHeader +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// Style 0 is the default, style 1 is the bold header.
+const xlsxStyles = xlsxHeader +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+func xlsxContentTypes(n int) string {
+	var b strings.Builder
+	b.WriteString(xlsxHeader)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func xlsxWorkbook(sheets []*xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(xlsxHeader)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	b.WriteString(`<sheets>`)
+	for i, sh := range sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sh.Name), i+1, i+1)
+	}
+	b.WriteString(`</sheets>`)
+	b.WriteString(`<definedNames>`)
+	for i, sh := range sheets {
+		if len(sh.Headers) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, `<definedName name="_xlnm._FilterDatabase" localSheetId="%d" hidden="1">%s</definedName>`,
+			i, xmlEscape("'"+strings.ReplaceAll(sh.Name, "'", "''")+"'!"+xlsxAbsRange(sh)))
+	}
+	b.WriteString(`</definedNames>`)
+	b.WriteString(`</workbook>`)
+	return b.String()
+}
+
+func xlsxWorkbookRels(n int) string {
+	var b strings.Builder
+	b.WriteString(xlsxHeader)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, n+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func xlsxWorksheet(sh *xlsxSheet) string {
+	var b strings.Builder
+	b.WriteString(xlsxHeader)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+
+	if len(sh.Headers) > 0 {
+		b.WriteString(`<cols>`)
+		for i, h := range sh.Headers {
+			width := len([]rune(h))
+			for _, r := range sh.Rows {
+				if i < len(r) {
+					width = max(width, len([]rune(xlsxDisplay(r[i]))))
+				}
+			}
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, min(width+2, 60))
+		}
+		b.WriteString(`</cols>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+	b.WriteString(`<row r="1">`)
+	for i, h := range sh.Headers {
+		fmt.Fprintf(&b, `<c r="%s1" s="1" t="inlineStr"><is><t>%s</t></is></c>`, xlsxColumnName(i), xmlEscape(h))
+	}
+	b.WriteString(`</row>`)
+	for ri, row := range sh.Rows {
+		rowNum := ri + 2
+		fmt.Fprintf(&b, `<row r="%d">`, rowNum)
+		for ci, v := range row {
+			ref := xlsxColumnName(ci) + strconv.Itoa(rowNum)
+			switch t := v.(type) {
+			case nil:
+				continue
+			case float64:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(t, 'f', -1, 64))
+			case int:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, t)
+			case bool:
+				bv := 0
+				if t {
+					bv = 1
+				}
+				fmt.Fprintf(&b, `<c r="%s" t="b"><v>%d</v></c>`, ref, bv)
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(fmt.Sprint(t)))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData>`)
+
+	if len(sh.Headers) > 0 {
+		fmt.Fprintf(&b, `<autoFilter ref="%s"/>`, xlsxRange(sh))
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+func xlsxDisplay(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func xlsxRange(sh *xlsxSheet) string {
+	return fmt.Sprintf("A1:%s%d", xlsxColumnName(len(sh.Headers)-1), len(sh.Rows)+1)
+}
+
+func xlsxAbsRange(sh *xlsxSheet) string {
+	return fmt.Sprintf("$A$1:$%s$%d", xlsxColumnName(len(sh.Headers)-1), len(sh.Rows)+1)
+}
+
+// xlsxColumnName converts a zero-based column index to its letters (0 -> A, 26 -> AA).
+func xlsxColumnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestXLSXColumnName(t *testing.T) {
+	tests := []struct {
+		i    int
+		want string
+	}{
+		{0, "A"}, {25, "Z"}, {26, "AA"}, {27, "AB"}, {51, "AZ"}, {52, "BA"}, {701, "ZZ"}, {702, "AAA"},
+	}
+	for _, tt := range tests {
+		if got := xlsxColumnName(tt.i); got != tt.want {
+			t.Errorf("xlsxColumnName(%d) = %q, want %q", tt.i, got, tt.want)
+		}
+	}
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int
+		want string
+	}{
+		{"US", 2, "US"},
+		{"a/b:c[d]", 2, "a_b_c_d_"},
+		{"  ", 3, "Sheet3"},
+		{"summary", 4, "Sheet4"},
+		{strings.Repeat("x", 40), 2, strings.Repeat("x", 31)},
+	}
+	for _, tt := range tests {
+		if got := xlsxSheetName(tt.name, tt.n); got != tt.want {
+			t.Errorf("xlsxSheetName(%q, %d) = %q, want %q", tt.name, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCheckBinaryOutput(t *testing.T) {
+	tests := []struct {
+		format   string
+		terminal bool
+		wantErr  bool
+	}{
+		{"xlsx", true, true},
+		{" XLSX ", true, true},
+		{"xlsx", false, false},
+		{"json", true, false},
+		{"html", true, false},
+	}
+	for _, tt := range tests {
+		if err := checkBinaryOutput(tt.format, tt.terminal); (err != nil) != tt.wantErr {
+			t.Errorf("checkBinaryOutput(%q, %v) = %v, want error %v", tt.format, tt.terminal, err, tt.wantErr)
+		}
+	}
+}
+
+// xlsxTestCell is a worksheet cell as written by xlsxWorksheet.
+type xlsxTestCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline string `xml:"is>t"`
+}
+
+type xlsxTestSheet struct {
+	Rows []struct {
+		Cells []xlsxTestCell `xml:"c"`
+	} `xml:"sheetData>row"`
+	AutoFilter struct {
+		Ref string `xml:"ref,attr"`
+	} `xml:"autoFilter"`
+}
+
+func TestPrintXLSX(t *testing.T) {
+	pop := func(n int) *int { return &n }
+	rows := []asoPopscoreRow{
+		{Keyword: "photo", Country: "US", Popularity: pop(40)},
+		{Keyword: "a & <b>", Country: "US"},
+		{Keyword: "photo", Country: "GB", Popularity: pop(20)},
+	}
+	var buf bytes.Buffer
+	if err := printXLSX(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("output is not a zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = b
+
+		// Every part must be well-formed XML.
+		dec := xml.NewDecoder(bytes.NewReader(b))
+		for {
+			if _, err := dec.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %v", f.Name, err)
+			}
+		}
+	}
+	for _, name := range []string{
+		"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml",
+		"xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml", "xl/worksheets/sheet3.xml",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	if len(files) != 8 {
+		t.Errorf("got %d parts, want 8", len(files))
+	}
+
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(files["xl/workbook.xml"], &wb); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range wb.Sheets {
+		names = append(names, s.Name)
+	}
+	if want := []string{"Summary", "US", "GB"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("sheets = %q, want %q", names, want)
+	}
+
+	var summary xlsxTestSheet
+	if err := xml.Unmarshal(files["xl/worksheets/sheet1.xml"], &summary); err != nil {
+		t.Fatal(err)
+	}
+	if got := xlsxTestRow(summary, 0); !reflect.DeepEqual(got, []string{"sheet", "rows", "found", "withPopularity", "averagePopularity", "maxPopularity"}) {
+		t.Errorf("summary header = %q", got)
+	}
+	if got := xlsxTestRow(summary, 1); !reflect.DeepEqual(got, []string{"US", "2", "0", "1", "40", "40"}) {
+		t.Errorf("summary US = %q", got)
+	}
+
+	var us xlsxTestSheet
+	if err := xml.Unmarshal(files["xl/worksheets/sheet2.xml"], &us); err != nil {
+		t.Fatal(err)
+	}
+	if us.AutoFilter.Ref == "" || !strings.HasPrefix(us.AutoFilter.Ref, "A1:") || !strings.HasSuffix(us.AutoFilter.Ref, "3") {
+		t.Errorf("autoFilter ref = %q", us.AutoFilter.Ref)
+	}
+	header := xlsxTestRow(us, 0)
+	kw, popCol := indexOf(header, "keyword"), indexOf(header, "popularity")
+	if kw < 0 || popCol < 0 {
+		t.Fatalf("sheet header = %q", header)
+	}
+	first := us.Rows[1].Cells
+	if c := xlsxTestFind(first, xlsxColumnName(popCol)+"2"); c == nil || c.Type != "" || c.Value != "40" {
+		t.Errorf("popularity cell = %+v, want numeric 40", c)
+	}
+	second := us.Rows[2].Cells
+	if c := xlsxTestFind(second, xlsxColumnName(kw)+"3"); c == nil || c.Type != "inlineStr" || c.Inline != "a & <b>" {
+		t.Errorf("keyword cell = %+v, want escaped inline string", c)
+	}
+	if c := xlsxTestFind(second, xlsxColumnName(popCol)+"3"); c != nil {
+		t.Errorf("unknown popularity written as %+v, want no cell", c)
+	}
+}
+
+func xlsxTestRow(sh xlsxTestSheet, i int) []string {
+	var out []string
+	for _, c := range sh.Rows[i].Cells {
+		if c.Type == "inlineStr" {
+			out = append(out, c.Inline)
+		} else {
+			out = append(out, c.Value)
+		}
+	}
+	return out
+}
+
+func xlsxTestFind(cells []xlsxTestCell, ref string) *xlsxTestCell {
+	for i := range cells {
+		if cells[i].Ref == ref {
+			return &cells[i]
+		}
+	}
+	return nil
+}
+
+func indexOf(list []string, s string) int {
+	for i, it := range list {
+		if it == s {
+			return i
+		}
+	}
+	return -1
+}